@@ -1,6 +1,9 @@
 package mspec
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // Assert is an interface used by each Specification that can used to
 // enforce a rule.
@@ -181,3 +184,27 @@ type Assert interface {
 	// Returns whether the assertion was successful (true) or not (false).
 	EqualError(theError error, errString string, msgAndArgs ...interface{}) bool
 }
+
+// Specified is implemented by an Assert that knows the Specification it
+// asserts, which is how SpecOf reaches the spec.  The default assertions
+// implement it, and custom assertions can by returning the Specification
+// they were constructed with.
+type Specified interface {
+	Specification() *Specification
+}
+
+// SpecOf returns the Specification that assert asserts, to reach the
+// context and lifecycle of the spec running:
+//
+//	it("should respond", func(assert Assert) {
+//	  status, err := client.Status(SpecOf(assert).Context())
+//	  ...
+//
+// SpecOf panics if assert does not implement Specified.
+func SpecOf(assert Assert) *Specification {
+	s, ok := assert.(Specified)
+	if !ok {
+		panic(fmt.Sprintf("mspec: %T does not implement Specified, to reach its Specification", assert))
+	}
+	return s.Specification()
+}
@@ -36,15 +36,34 @@ type mspectTestingT struct {
 // This specific method sets an internal mspec flag so that the framework
 // is aware that an error occurred.
 func (m *mspectTestingT) Errorf(format string, args ...interface{}) {
+	// a spec that timed out is no longer reported
+	if m.spec.abandoned() {
+		return
+	}
+
 	// because we control the output of specification, we
 	// need to store these details in a state for later use in
 	// the bdd framework.  to do that, we use the
 	// m.spec.AssertionFailed boolean.
 	m.spec.AssertionFailed = true
 
-	// parse out Testify's location info by removing the first
-	// line and reformat their Error message to our liking
-	// using string foo
+	out := errorMessage(format, args...)
+
+	m.spec.PrintSpecWithError()
+	// to propertly set the caller used, we currently need to call
+	// m.spec.PrinterError here to capture the proper line number.
+	// and since PrintError() comes after PrintTitleWithError(),
+	// we have the line above.
+	//
+	// TODO refactor to pass the caller information down along with
+	// the custom error message parsing.  that way we can control the
+	// printing internally and seal up these Print*() messages.
+	m.spec.PrintError(out)
+}
+
+// errorMessage parses out Testify's location info by removing the first
+// line and reformats their Error message to our liking using string foo.
+func errorMessage(format string, args ...interface{}) string {
 	err := fmt.Sprintf(format, args...)
 	err = strings.Replace(err, "\r", "", -1)
 	err = strings.Replace(err, "        ", "\t\t\t", -1) // some errors are two-liners
@@ -63,23 +82,35 @@ func (m *mspectTestingT) Errorf(format string, args ...interface{}) {
 			out = strings.Join([]string{out, "\n", lines[i]}, "")
 		}
 	}
+	return out
+}
 
-	m.spec.PrintSpecWithError()
-	// to propertly set the caller used, we currently need to call
-	// m.spec.PrinterError here to capture the proper line number.
-	// and since PrintError() comes after PrintTitleWithError(),
-	// we have the line above.
-	//
-	// TODO refactor to pass the caller information down along with
-	// the custom error message parsing.  that way we can control the
-	// printing internally and seal up these Print*() messages.
-	m.spec.PrintError(out)
+// assertions are the default Assert: Testify's asserts along with the
+// lifecycle of the spec they assert.
+type assertions struct {
+	*asserts.Assertions
+	spec *Specification
+}
+
+// Specification returns the spec the assertions assert.  See SpecOf().
+func (a *assertions) Specification() *Specification {
+	return a.spec
+}
+
+// testingT is what the default assertions of the spec fail.  The asserts
+// of the spec's own methods fail it directly, for the failing line to be
+// found as many callers up as that of any other assertion.
+func (spec *Specification) testingT() asserts.TestingT {
+	return &mspectTestingT{spec: spec}
 }
 
 // newAssertions constructs a wrapper around Testify's asserts.
-func newAssertions(s *Specification) *asserts.Assertions {
-	return asserts.New(
-		&mspectTestingT{
-			spec: s,
-		})
+func newAssertions(s *Specification) Assert {
+	return &assertions{
+		Assertions: asserts.New(
+			&mspectTestingT{
+				spec: s,
+			}),
+		spec: s,
+	}
 }
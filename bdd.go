@@ -5,71 +5,159 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
-func (spec *Specification) run() {
+// execute runs the assertions of the spec, within timeout when it is not
+// zero, and prints the spec.
+func (spec *Specification) execute(timeout time.Duration) {
 
-	// execute the Assertion
-	spec.AssertFn(config.assertFn(spec))
+	if timeout > 0 {
+		spec.runWithin(timeout)
+	} else {
+		spec.assertAll()
+	}
 
 	// if there was no error (which handles its own printing),
 	// print the spec here.
-	if spec.notImplemented {
+	switch {
+	case spec.abandoned():
+	case spec.focused && spec.run.ci:
+		spec.failFocused()
+	case spec.notImplemented && spec.run.strict:
+		spec.run.pending++
+		spec.failNotImplemented()
+	case spec.notImplemented:
 		spec.PrintSpecNotImplemented()
-	} else if !spec.AssertionFailed {
+	case spec.AssertionFailed:
+	case spec.skipReason != "":
+		spec.PrintSpecSkipped(spec.skipReason)
+	case spec.focused:
+		spec.PrintSpecFocused()
+	default:
 		spec.PrintSpec()
 	}
 }
 
+// assertAll runs every assertion func in order, each with its own Assert,
+// until one skips the spec or the spec is abandoned.
+func (spec *Specification) assertAll() {
+	for i, assertFn := range spec.assertFns {
+		if spec.abandoned() {
+			return
+		}
+		spec.assert(i, assertFn)
+		if spec.skipReason != "" {
+			break
+		}
+	}
+}
+
+// assert runs the i-th assertion func of the spec.  A panic fails the spec
+// but does not stop the assertions after it.
+func (spec *Specification) assert(i int, assertFn func(Assert)) {
+	defer spec.recoverPanic()
+	spec.AssertFn = assertFn
+	spec.assertion = i
+	spec.AssertFn(spec.run.config.assertFn(spec))
+}
+
 // Given defines the Feature's specific context to be spec'd out.
+//
+// Every Given, When and It is run as a nested subtest named after its text,
+// so a single spec can be targeted with go test's -run flag:
+//
+//	go test -run 'Test_Washing_Dogs/a_dog.*/the_dog_is_washed/should_be_clean'
+//
+// Each Given renders with its own run context, so Givens may be called from
+// tests that use t.Parallel().
+//
+// The Given and When closures run once and their context is shared by all of
+// the specs.  After SetIsolated(), they run again from scratch for each It.
 func Given(t *testing.T, given string, when ...func(When)) {
+	runGiven(t, featureDesc(2), given, declaration{}, when)
+}
+
+// declaration is how a Given was declared.
+type declaration struct {
+	mark mark
+	name string     // the name of its subtest, if not its text
+	row  Row        // the row of the Examples of an Outline
+	b    *testing.B // the benchmark of a GivenBenchmark
+	fuzz bool       // whether the Given runs an input of a GivenFuzz
+}
+
+// runGiven runs a Given of the feature, as it was declared.
+func runGiven(t *testing.T, feature, given string, d declaration, when []func(When)) {
 
 	// setup the spec that we will be using
 	spec := &Specification{
 		T:       t,
-		Feature: featureDesc(2),
+		Feature: feature,
 		Given:   given,
+		row:     d.row,
+		b:       d.b,
+		run:     newRunContext(),
+	}
+	if d.fuzz {
+		spec.run.fuzz = t
+	}
+	// a flag that cannot be parsed fails the Given, rather than each spec
+	if err := spec.run.flagErr; err != nil {
+		if t == nil {
+			panic(err.Error())
+		}
+		t.Helper()
+		t.Fatal(err)
 	}
 	spec.PrintFeature()
-	spec.PrintContext()
-
-	for _, whenFn := range when {
-		whenFn(func(when string, its ...func(It)) {
-
-			spec.When = when
-			spec.PrintWhen()
-
-			for _, itFn := range its {
-				itFn(func(it string, assertFns ...func(Assert)) {
-
-					spec.Spec = it
-					// Spec output is handled in the spec.run() below
-
-					if len(assertFns) > 0 {
-						// having at least 1 assert means we are implemented
-						for _, assertFn := range assertFns {
-							spec.AssertFn = assertFn
-							spec.notImplemented = false
-						}
-					} else {
-						spec.AssertFn = notImplemented()
-						spec.notImplemented = true
-					}
-
-					// run() handles contextual printing and some delegation
-					// to the Assert's implementation for error handling
-					spec.run()
-				})
-			}
-		})
+	spec.run.shuffle(feature, given)
+
+	// the output is buffered and written in one piece once the Given
+	// completes so concurrent Givens do not interleave.
+	defer spec.run.countPending(t)
+	defer spec.run.flush()
+
+	name := given
+	if d.name != "" {
+		name = d.name
 	}
+	subtest(t, name, func(t *testing.T) {
+		defer spec.run.printf("\n")
+
+		if d.mark.skip {
+			spec.PrintContextSkipped(d.mark.reason)
+			skip(t, d.mark.reason)
+			return
+		}
+		spec.PrintContext()
+		spec.PrintExample()
+
+		given := *spec
+		given.T = t
+		defer spec.run.tearDown(given)
 
-	// reset to default
-	config.resetLasts()
+		g := &scope{spec: *spec, mark: d.mark}
+		g.given(t, when)
+	})
+}
 
-	if config.output != outputNone {
-		fmt.Println()
+// subtest runs fn as a subtest of t named after text.  A nil or hand-built
+// testing.T, as used by Example() and the benchmarks, cannot host subtests,
+// so fn is run inline against t instead.
+func subtest(t *testing.T, text string, fn func(*testing.T)) {
+	if t == nil || t.Name() == "" {
+		fn(t)
+		return
 	}
+	t.Run(subtestName(text), fn)
+}
+
+// subtestName flattens the multi-line text of a Given, When or It into a
+// single line, without its labels.  go test then replaces the spaces with underscores.
+func subtestName(text string) string {
+	text, _ = splitLabels(text)
+	return strings.Join(strings.Fields(text), " ")
 }
 
 // When defines the action or event when Given a specific context.
@@ -79,12 +167,17 @@ type When func(when string, it ...func(It))
 type It func(title string, assert ...func(Assert))
 
 // Setup is used to define before/after (setup/teardown) functions.
+// before runs when the decorated spec executes, and after runs once it
+// completes, even when it failed or panicked.
+//
+// See also the BeforeEach and AfterEach methods of When and It, which apply
+// to every spec of a Given or a When.
 func Setup(before, after func()) func(fn func(Assert)) func(Assert) {
 	return func(fn func(Assert)) func(Assert) {
-		before()
 		return func(assert Assert) {
+			before()
+			defer after()
 			fn(assert)
-			after()
 		}
 	}
 }
@@ -101,7 +194,13 @@ var featureDesc = func(callerDepth int) string {
 	m := fmt.Sprintf("%s", runtime.FuncForPC(pc).Name())
 	i := strings.LastIndex(m, ".")
 	m = m[i+1 : len(m)]
-	m = strings.Replace(m, "Test_", "", 1)
-	m = strings.Replace(m, "Test", "", 1)
+	if strings.HasPrefix(m, "Benchmark") {
+		m = strings.TrimPrefix(strings.TrimPrefix(m, "Benchmark"), "_")
+	} else if strings.HasPrefix(m, "Fuzz") {
+		m = strings.TrimPrefix(strings.TrimPrefix(m, "Fuzz"), "_")
+	} else {
+		m = strings.Replace(m, "Test_", "", 1)
+		m = strings.Replace(m, "Test", "", 1)
+	}
 	return strings.Replace(m, "_", " ", -1)
 }
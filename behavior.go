@@ -0,0 +1,73 @@
+package mspec
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+
+	"github.com/eduncan911/go-mspec/colors"
+)
+
+// Behavior is a named group of Whens and Its, shared by every Given that
+// includes it for its own subject.
+type Behavior[S any] struct {
+	name string
+	when []func(When, S)
+}
+
+// DefineBehavior defines the Whens, and their Its, that every subject of
+// the behavior must satisfy:
+//
+//	var aRepository = DefineBehavior("a repository", func(when When, repo Repository) {
+//
+//		when("a user is saved", func(it It) {
+//			err := repo.Save(user)
+//
+//			it("should persist the user", func(assert Assert) {
+//				assert.NoError(err)
+//			})
+//			it("should find the user", func(assert Assert) {
+//				found, _ := repo.Find(user.ID)
+//				assert.Equal(user, found)
+//			})
+//		})
+//	})
+func DefineBehavior[S any](name string, when ...func(When, S)) Behavior[S] {
+	return Behavior[S]{name: name, when: when}
+}
+
+// For includes the behavior in a Given for subject.  Its Whens and Its
+// render in the Given as if they were declared in it, and their failures
+// say where the behavior was included:
+//
+//	Given(t, "a memory repository", aRepository.For(NewMemoryRepository()))
+//
+//	Given(t, "a SQL repository", func(when When) {
+//		...
+//	}, aRepository.For(sqlRepository))
+//
+// The subject is shared by the specs of the behavior.  For a fresh subject
+// in each spec, run isolated and make the subject a func that returns one.
+func (b Behavior[S]) For(subject S) func(When) {
+	_, file, line, _ := runtime.Caller(1)
+	site := fmt.Sprintf("behaves like %s, included at %s:%d", b.name, path.Base(file), line)
+
+	return func(w When) {
+		sc := w.scope()
+		outer := sc.spec.behavior
+		sc.spec.behavior = site
+		defer func() { sc.spec.behavior = outer }()
+
+		for _, fn := range b.when {
+			fn(w, subject)
+		}
+	}
+}
+
+// PrintBehavior prints where the behavior of a failing spec was included.
+func (spec *Specification) PrintBehavior() {
+	if spec.behavior == "" {
+		return
+	}
+	spec.run.printf("%s        %s%s\n", spec.run.config.AnsiOfCode, spec.behavior, colors.Reset)
+}
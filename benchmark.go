@@ -0,0 +1,138 @@
+package mspec
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/eduncan911/go-mspec/colors"
+)
+
+// GivenBenchmark is a Given for a benchmark.  The actions of its Whens are
+// measured with it.Measure, and the Its assert on the budgets they have:
+//
+//	func Benchmark_Sorting(b *testing.B) {
+//		GivenBenchmark(b, "a thousand shuffled ints", func(when When) {
+//			ints := shuffled(1000)
+//
+//			when("sorted", func(it It) {
+//				sorting := it.Measure(func() {
+//					sort.Ints(append([]int(nil), ints...))
+//				})
+//
+//				it("should take under 100µs", sorting.MaxNsPerOp(100000))
+//				it("should allocate once", sorting.MaxAllocsPerOp(1))
+//			})
+//		})
+//	}
+//
+// Each measured action runs as a sub-benchmark named after its Given and
+// When, so go test -bench reports it as any other benchmark.
+func GivenBenchmark(b *testing.B, given string, when ...func(When)) {
+	// the specs run against a stand-in for a test, and fail the benchmark
+	t := &testing.T{}
+	runGiven(t, featureDesc(2), given, declaration{b: b}, when)
+	if t.Failed() {
+		b.Fail()
+	}
+}
+
+// Measurement is what measuring an action found.  It is filled in once the
+// action has been measured, before the Its declared after it run.
+type Measurement struct {
+	testing.BenchmarkResult
+}
+
+// Measure benchmarks action, running it b.N times, and returns the
+// Measurement of it for the Its of the When to assert on.  Outside of a
+// GivenBenchmark, the action is measured with testing.Benchmark.
+func (it It) Measure(action func()) *Measurement {
+	sc := it.scope()
+	m := &Measurement{}
+	if sc.plan != nil {
+		// the specs of an isolated run are being discovered
+		return m
+	}
+
+	bench := func(b *testing.B) {
+		b.ReportAllocs()
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		for b.Loop() {
+			action()
+		}
+		runtime.ReadMemStats(&after)
+
+		m.BenchmarkResult = testing.BenchmarkResult{
+			N:         b.N,
+			T:         b.Elapsed(),
+			MemAllocs: after.Mallocs - before.Mallocs,
+			MemBytes:  after.TotalAlloc - before.TotalAlloc,
+		}
+	}
+	if b := sc.spec.b; b != nil {
+		b.Run(subtestName(sc.spec.Given+" "+sc.spec.whens()), bench)
+	} else {
+		testing.Benchmark(bench)
+	}
+
+	sc.spec.PrintMeasurement(m)
+	return m
+}
+
+// MaxNsPerOp asserts that the action took at most ns nanoseconds per run.
+func (m *Measurement) MaxNsPerOp(ns int64) func(Assert) {
+	return m.budget(m.NsPerOp, ns, "ns/op")
+}
+
+// MaxAllocsPerOp asserts that the action allocated at most allocs times per
+// run.
+func (m *Measurement) MaxAllocsPerOp(allocs int64) func(Assert) {
+	return m.budget(m.AllocsPerOp, allocs, "allocs/op")
+}
+
+// MaxBytesPerOp asserts that the action allocated at most bytes per run.
+func (m *Measurement) MaxBytesPerOp(bytes int64) func(Assert) {
+	return m.budget(m.AllocedBytesPerOp, bytes, "B/op")
+}
+
+// budget asserts that perOp of the action is at most max.  It must be
+// called by the Max funcs, for a failure to be reported at the It that
+// they were called in rather than in here.
+func (m *Measurement) budget(perOp func() int64, max int64, unit string) func(Assert) {
+	_, file, line, _ := runtime.Caller(2)
+	return func(assert Assert) {
+		var message string
+		switch {
+		case m.N == 0:
+			message = "the action was not measured"
+		case perOp() > max:
+			message = fmt.Sprintf("%d %s is over the budget of %d %s", perOp(), unit, max, unit)
+		default:
+			return
+		}
+
+		// custom assertions that do not reach their spec fail as they are
+		s, ok := assert.(Specified)
+		if !ok {
+			assert.True(false, message)
+			return
+		}
+		s.Specification().failAt(file, line, "\tError:\t\t"+message)
+	}
+}
+
+// String returns the measurement as go test -bench reports it.
+func (m *Measurement) String() string {
+	return fmt.Sprintf("%d ns/op  %d B/op  %d allocs/op", m.NsPerOp(), m.AllocedBytesPerOp(), m.AllocsPerOp())
+}
+
+// PrintMeasurement prints what measuring the action of the When found.
+func (spec *Specification) PrintMeasurement(m *Measurement) {
+	c := spec.run.config
+	if m.N == 0 {
+		spec.run.printf("%s    %s  not measured%s\n", c.AnsiOfCode, spec.indent(), colors.Reset)
+		return
+	}
+	spec.run.printf("%s    %s  measured %d runs: %s%s\n", c.AnsiOfCode, spec.indent(), m.N, m, colors.Reset)
+}
@@ -0,0 +1,96 @@
+package mspec
+
+import (
+	"fmt"
+	"sync"
+)
+
+// cleanups are the funcs registered to clean up after a spec or a When.
+// The assertions of a spec with a timeout register them from their own
+// goroutine.
+type cleanups struct {
+	mu  sync.Mutex
+	fns []func()
+}
+
+func (c *cleanups) add(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fns = append(c.fns, fn)
+}
+
+// run runs the cleanups last registered first, each of them even if one
+// before it panics, and hands the panics to failed.
+func (c *cleanups) run(failed func(value interface{}, stack []string)) {
+	c.mu.Lock()
+	fns := c.fns
+	c.fns = nil
+	c.mu.Unlock()
+
+	for i := len(fns) - 1; i >= 0; i-- {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					failed(fmt.Sprintf("in a cleanup: %v", r), panicStack())
+				}
+			}()
+			fns[i]()
+		}()
+	}
+}
+
+// Cleanup registers fn to run once the spec completes, after its AfterEach
+// hooks, even when the spec failed, panicked or was skipped.  The cleanups
+// run last registered first.  A cleanup that panics fails the spec.
+//
+//	f, _ := os.CreateTemp("", "spec")
+//	SpecOf(assert).Cleanup(func() { os.Remove(f.Name()) })
+func (spec *Specification) Cleanup(fn func()) {
+	if spec.cleanups == nil {
+		panic("mspec: Cleanup can only be called while the spec runs")
+	}
+	spec.cleanups.add(fn)
+}
+
+// Cleanup registers fn to run once the Given completes, after the last of
+// its specs and its AfterAll hooks.  See It.Cleanup().
+func (w When) Cleanup(fn func()) {
+	w.scope().cleanups.add(fn)
+}
+
+// Cleanup registers fn to run once the When completes, after the last of
+// its specs and its AfterAll hooks, even when a spec failed or the closure
+// of the When panicked.  The cleanups run last registered first.  A cleanup
+// that panics fails the When.
+//
+//	when("a server is listening", func(it It) {
+//		srv := httptest.NewServer(handler)
+//		it.Cleanup(srv.Close)
+//		...
+//
+// In isolated mode, as the closure of the When runs again for every spec,
+// so do its cleanups.
+func (it It) Cleanup(fn func()) {
+	it.scope().cleanups.add(fn)
+}
+
+// cleanUp runs the cleanups of the spec, failing it if any of them panic.
+func (spec *Specification) cleanUp() {
+	spec.cleanups.run(func(value interface{}, stack []string) {
+		// a spec that timed out may still be running its assertions
+		if spec.abandoned() {
+			return
+		}
+		spec.AssertFn = nil
+		spec.PrintPanic(value, stack)
+	})
+}
+
+// cleanUp runs the cleanups of the When, failing it if any of them panic.
+func (sc *scope) cleanUp() {
+	sc.cleanups.run(func(value interface{}, stack []string) {
+		spec := sc.spec
+		spec.T = sc.t
+		spec.PrintPanic(value, stack)
+	})
+}
@@ -0,0 +1,104 @@
+package mspec
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	asserts "github.com/eduncan911/go-mspec/assert"
+)
+
+// attempt is the Assert of a single attempt of Eventually or Consistently.
+// It records the failures of the attempt instead of reporting them.
+type attempt struct {
+	failures []string
+}
+
+func (a *attempt) Errorf(format string, args ...interface{}) {
+	msg := errorMessage(format, args...)
+	msg = strings.TrimSpace(strings.Replace(msg, "Error:", "", 1))
+	a.failures = append(a.failures, strings.Join(strings.Fields(msg), " "))
+}
+
+// try runs fn once, and returns why it failed or "" when it passed.
+func (spec *Specification) try(fn func(Assert)) (failure string) {
+	rec := &attempt{}
+	defer func() {
+		switch r := recover().(type) {
+		case nil:
+		case skipped:
+			// skipping stops the spec, not only the attempt
+			panic(r)
+		default:
+			failure = fmt.Sprintf("panic: %v", r)
+		}
+	}()
+	fn(&assertions{Assertions: asserts.New(rec), spec: spec})
+	return strings.Join(rec.failures, "; ")
+}
+
+// Eventually asserts that the assertions made by fn all pass within
+// timeout, running fn again every interval until they do.  The failure
+// reports the last failure of fn and how many attempts were made.
+//
+//	SpecOf(assert).Eventually(func(assert Assert) {
+//		assert.Equal("shipped", order.Status())
+//	}, time.Second, 10*time.Millisecond)
+//
+// Returns whether the assertion was successful (true) or not (false).
+func (spec *Specification) Eventually(fn func(Assert), timeout, interval time.Duration, msgAndArgs ...interface{}) bool {
+	ctx := spec.Context()
+	deadline := time.Now().Add(timeout)
+
+	for attempts := 1; ; attempts++ {
+		failure := spec.try(fn)
+		if failure == "" {
+			return true
+		}
+
+		wait := time.NewTimer(interval)
+		if time.Now().Add(interval).After(deadline) {
+			wait.Stop()
+			return asserts.Fail(spec.testingT(), fmt.Sprintf("Eventually did not pass within %v after %d attempts\nlast failure: %s", timeout, attempts, failure), msgAndArgs...)
+		}
+		select {
+		case <-ctx.Done():
+			wait.Stop()
+			return asserts.Fail(spec.testingT(), fmt.Sprintf("Eventually did not pass before the spec's %v after %d attempts\nlast failure: %s", ctx.Err(), attempts, failure), msgAndArgs...)
+		case <-wait.C:
+		}
+	}
+}
+
+// Consistently asserts that the assertions made by fn keep passing for the
+// whole duration, running fn again every interval, and fails as soon as any
+// of them fail.  The failure reports the failure of fn and how many
+// attempts were made.
+//
+//	SpecOf(assert).Consistently(func(assert Assert) {
+//		assert.Empty(inbox.Messages())
+//	}, 100*time.Millisecond, 10*time.Millisecond)
+//
+// Returns whether the assertion was successful (true) or not (false).
+func (spec *Specification) Consistently(fn func(Assert), duration, interval time.Duration, msgAndArgs ...interface{}) bool {
+	ctx := spec.Context()
+	deadline := time.Now().Add(duration)
+
+	for attempts := 1; ; attempts++ {
+		if failure := spec.try(fn); failure != "" {
+			return asserts.Fail(spec.testingT(), fmt.Sprintf("Consistently did not hold for %v, failing on attempt %d\nfailure: %s", duration, attempts, failure), msgAndArgs...)
+		}
+
+		wait := time.NewTimer(interval)
+		if time.Now().Add(interval).After(deadline) {
+			wait.Stop()
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			wait.Stop()
+			return asserts.Fail(spec.testingT(), fmt.Sprintf("Consistently was stopped by the spec's %v after %d attempts", ctx.Err(), attempts), msgAndArgs...)
+		case <-wait.C:
+		}
+	}
+}
@@ -0,0 +1,31 @@
+Feature: Washing dogs
+  Dogs get painted, and their owners want them back to normal.
+
+  Background:
+    Given a dog
+
+  Scenario: Washing off washable paint
+    Given the dog is painted "red"
+    And the paint is washable
+    When the dog is washed
+    Then the dog should be "brown"
+    And the dog should have been washed 1 time
+
+  Scenario Outline: Paint that does not wash off
+    Given the dog is painted "<color>"
+    When the dog is washed
+    Then the wash should fail with:
+      """
+      The paint is not washable!
+      """
+    And the dog should be "<color>"
+
+    Examples:
+      | color |
+      | blue  |
+      | green |
+
+  @vet
+  Scenario: Taking the dog to the vet
+    When the dog visits the vet
+    Then the dog should be calm
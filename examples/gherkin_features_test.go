@@ -0,0 +1,48 @@
+package examples
+
+import (
+	. "github.com/eduncan911/go-mspec"
+	"github.com/eduncan911/go-mspec/gherkin"
+	"testing"
+)
+
+func Test_Gherkin_Features(t *testing.T) {
+
+	// example of running the scenarios that product owners write in
+	// Gherkin against Go step definitions.  the scenario about the vet
+	// has a step that is not defined yet, so it is NOT IMPLEMENTED.
+
+	var d *dog
+	var err error
+
+	steps := &Steps{}
+	steps.Before(func() {
+		d, err = nil, nil
+	})
+	steps.Step(`a dog`, func() {
+		d = BirthDog()
+	})
+	steps.Step(`the dog is painted {string}`, func(color string) {
+		d.Paint(&paint{color: color})
+	})
+	steps.Step(`the paint is washable`, func() {
+		d.paint.iswashable = true
+	})
+	steps.Step(`the dog is washed`, func() {
+		err = d.Wash()
+	})
+	steps.Step(`the dog visits the vet`, func() {
+		d.VisitVet()
+	})
+	steps.Step(`the dog should be {string}`, func(assert Assert, color string) {
+		assert.Equal(color, d.color)
+	})
+	steps.Step(`^the dog should have been washed (\d+) times?$`, func(assert Assert, times int) {
+		assert.Equal(times, d.timesWashed)
+	})
+	steps.Step(`the wash should fail with:`, func(assert Assert, message gherkin.DocString) {
+		assert.EqualError(err, message.Content)
+	})
+
+	Features(t, "features/*.feature", steps)
+}
@@ -22,6 +22,13 @@ import (
 	Optionally, you can use the Setup(before, after) feature to specify
 	a context to setup a context before each spec is run, as well a
 	teardown method to run after each spec is run.
+
+	The BeforeEach/AfterEach and BeforeAll/AfterAll hooks can also be
+	registered on a Given's when or a When's it, applying to every
+	spec nested within.
+
+	Or, call SetIsolated() to have the Given and When run again from
+	scratch for each spec.
 */
 
 func Test_Setup_Shared_Context(t *testing.T) {
@@ -138,3 +145,111 @@ func Test_Setup_NonShared_Context(t *testing.T) {
 	*/
 
 }
+
+func Test_Setup_Hooks(t *testing.T) {
+
+	// this example shows the lifecycle hooks.  the hooks registered on the
+	// Given's when apply to every spec of the Given, and the ones registered
+	// on the When's it apply to every spec of that When.  the after hooks
+	// run even when a spec fails or panics.
+	//
+
+	Given(t, "a dog at the groomers", func(when When) {
+
+		d := BirthDog()
+
+		when.BeforeEach(func() {
+			d.steps++ // the dog walks onto the table before each spec
+		})
+
+		when.AfterAll(func() {
+			d.steps = 0 // and goes home after the last one
+		})
+
+		when("the dog is painted and washed", func(it It) {
+
+			it.BeforeEach(func() {
+				d.Paint(&paint{color: "blue", iswashable: true})
+			})
+
+			it.AfterEach(func() {
+				d.Wash()
+			})
+
+			it("should be painted blue", func(assert Assert) {
+				assert.Equal("blue", d.color)
+				assert.Equal(1, d.steps)
+			})
+
+			it("should have been washed after the spec before it", func(assert Assert) {
+				assert.Equal(1, d.timesWashed)
+				assert.Equal(2, d.steps)
+			})
+		})
+	})
+
+	/* Outputs:
+
+	Feature: Setup Hooks
+	  Given a dog at the groomers
+	    When the dog is painted and washed
+	    » It should be painted blue
+	    » It should have been washed after the spec before it
+
+	*/
+
+}
+
+func Test_Setup_Isolated_Context(t *testing.T) {
+
+	// this example shows the isolated mode where the Given and When are
+	// run again from scratch for each spec.  every spec gets a fresh dog
+	// and fresh paint, and the dog is washed just once for each spec.
+	//
+
+	SetIsolated()
+	defer SetShared()
+
+	Given(t, "a dog that has been painted\nand the paint is washable", func(when When) {
+
+		d := BirthDog()
+		d.Paint(&paint{
+			color:      "red",
+			iswashable: true,
+		})
+
+		when("washing the dog", func(it It) {
+
+			err := d.Wash()
+
+			it("should not have an error", func(assert Assert) {
+				assert.NoError(err)
+			})
+
+			it("should have timesWashed be only 1 time", func(assert Assert) {
+				assert.Equal(1, d.timesWashed)
+
+				// wash again, which no other spec will see
+				d.paint = &paint{iswashable: true}
+				d.Wash()
+			})
+
+			it("should still have timesWashed be only 1 time", func(assert Assert) {
+				assert.Equal(1, d.timesWashed)
+			})
+		})
+	})
+
+	/* Outputs:
+
+	Feature: Setup Isolated Context
+	  Given a dog that has been painted
+	  and the paint is washable
+	    When washing the dog
+	    » It should not have an error
+	    » It should have timesWashed be only 1 time
+	    » It should still have timesWashed be only 1 time
+
+	*/
+
+}
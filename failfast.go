@@ -0,0 +1,66 @@
+package mspec
+
+// failedFast is the reason given for the specs skipped after a spec before
+// them failed, when failing fast.
+const failedFast = "fail fast, a spec before it failed"
+
+// SetFailFast skips the remaining specs of a Given once one of its specs
+// fails, so that a failing foundation does not flood the output with the
+// failures that follow from it.
+func SetFailFast() {
+	configMu.Lock()
+	defer configMu.Unlock()
+	config.failFast = true
+}
+
+// SetContinueOnFailure runs every spec no matter how many fail (default).
+func SetContinueOnFailure() {
+	configMu.Lock()
+	defer configMu.Unlock()
+	config.failFast = false
+}
+
+// FailFast skips the remaining specs of the Given once one of its specs
+// fails.  See SetFailFast.
+func (w When) FailFast() {
+	w.scope().failFast = true
+}
+
+// FailFast skips the remaining specs of the When once one of its specs
+// fails.  See SetFailFast.
+func (it It) FailFast() {
+	it.scope().failFast = true
+}
+
+// failsFast reports whether sc stops running its specs after a failure.
+func (sc *scope) failsFast() bool {
+	if sc.failFast {
+		return true
+	}
+	// the configuration applies to the Givens
+	return sc.spec.When == "" && sc.spec.run.config.failFast
+}
+
+// stopped reports whether a spec of sc, or of a scope it is nested in,
+// failed while failing fast.
+func (sc *scope) stopped() bool {
+	for ; sc != nil; sc = sc.parent {
+		if sc.spec.run.stopped[sc.key()] {
+			return true
+		}
+	}
+	return false
+}
+
+// stopOnFailure stops the scopes that fail fast once the spec failed.  It
+// must be deferred before the spec's panics are recovered.
+func (sc *scope) stopOnFailure(spec *Specification) {
+	if !spec.AssertionFailed && !spec.abandoned() {
+		return
+	}
+	for ; sc != nil; sc = sc.parent {
+		if sc.failsFast() {
+			sc.spec.run.stopped[sc.key()] = true
+		}
+	}
+}
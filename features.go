@@ -0,0 +1,390 @@
+package mspec
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eduncan911/go-mspec/gherkin"
+)
+
+// Steps are the Go step definitions that the steps of Gherkin features are
+// matched to.  The zero value is ready to use.
+type Steps struct {
+	defs   []*stepDef
+	before []func()
+}
+
+// stepDef is a step definition: the expression steps are matched with and
+// the func they run.
+type stepDef struct {
+	expr    string
+	re      *regexp.Regexp
+	fn      reflect.Value
+	asserts bool // whether fn takes the Assert of a Then step first
+}
+
+var (
+	assertType    = reflect.TypeOf((*Assert)(nil)).Elem()
+	tableType     = reflect.TypeOf(gherkin.Table(nil))
+	docStringType = reflect.TypeOf(gherkin.DocString{})
+)
+
+// Step defines the func that the steps matching expr run.  The expression
+// is a regular expression when it starts with ^ or ends with $, and is
+// otherwise matched as a whole, with the {int}, {float}, {word} and
+// {string} parameters of Cucumber expressions:
+//
+//	steps.Step(`the dog is painted {string}`, func(color string) {
+//		dog.Paint(color)
+//	})
+//	steps.Step(`^the dog is washed (\d+) times?$`, func(times int) {
+//		dog.Wash(times)
+//	})
+//	steps.Step(`the dog should be {string}`, func(assert Assert, color string) {
+//		assert.Equal(color, dog.Color())
+//	})
+//
+// The func takes an argument for each parameter, or group, of the
+// expression, as a string, bool or number.  The funcs of Then steps also
+// take the Assert of their spec first.  The data table, or doc string, of a
+// step is passed last to a func that takes a gherkin.Table, or a
+// gherkin.DocString.
+//
+// Step panics if fn does not match expr.
+func (s *Steps) Step(expr string, fn interface{}) {
+	re, err := regexp.Compile(stepExpression(expr))
+	if err != nil {
+		panic(fmt.Sprintf("mspec: the step %q: %v", expr, err))
+	}
+	def := &stepDef{expr: expr, re: re, fn: reflect.ValueOf(fn)}
+	if err := def.check(); err != nil {
+		panic(fmt.Sprintf("mspec: the step %q: %v", expr, err))
+	}
+	s.defs = append(s.defs, def)
+}
+
+// Before registers fn to run before the steps of each scenario, to start
+// them over with a fresh context.
+func (s *Steps) Before(fn func()) {
+	s.before = append(s.before, fn)
+}
+
+// cucumberParams are the parameters of Cucumber expressions.
+var cucumberParams = strings.NewReplacer(
+	regexp.QuoteMeta("{int}"), `(-?\d+)`,
+	regexp.QuoteMeta("{float}"), `(-?\d*\.?\d+)`,
+	regexp.QuoteMeta("{word}"), `(\S+)`,
+	regexp.QuoteMeta("{string}"), `"([^"]*)"`,
+)
+
+// stepExpression returns the regular expression of a step expression.
+func stepExpression(expr string) string {
+	if strings.HasPrefix(expr, "^") || strings.HasSuffix(expr, "$") {
+		return expr
+	}
+	return "^" + cucumberParams.Replace(regexp.QuoteMeta(expr)) + "$"
+}
+
+// check reports whether the func of the definition can take what the
+// steps it matches are given.
+func (d *stepDef) check() error {
+	t := d.fn.Type()
+	if t.Kind() != reflect.Func {
+		return fmt.Errorf("%v is not a func", t)
+	}
+	in := make([]reflect.Type, t.NumIn())
+	for i := range in {
+		in[i] = t.In(i)
+	}
+	if len(in) > 0 && in[0] == assertType {
+		d.asserts = true
+		in = in[1:]
+	}
+	if n := len(in); n > 0 && (in[n-1] == tableType || in[n-1] == docStringType) {
+		in = in[:n-1]
+	}
+	if len(in) != d.re.NumSubexp() || t.IsVariadic() {
+		return fmt.Errorf("%v does not take the %d parameters of the step", t, d.re.NumSubexp())
+	}
+	for _, arg := range in {
+		switch arg.Kind() {
+		case reflect.String, reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+		default:
+			return fmt.Errorf("a step cannot be given a %v", arg)
+		}
+	}
+	return nil
+}
+
+// match returns the definition of the first step defined that matches
+// step, or nil if none do.
+func (s *Steps) match(step *gherkin.Step) *stepDef {
+	for _, def := range s.defs {
+		if def.re.MatchString(step.Text) {
+			return def
+		}
+	}
+	return nil
+}
+
+// call runs the definition for step.  The assert is nil for the Given and
+// When steps.
+func (d *stepDef) call(assert Assert, step *gherkin.Step) {
+	if d.asserts && assert == nil {
+		panic(fmt.Sprintf("mspec: the %s step %q asserts, which only Then steps can", step.Kind, step.Text))
+	}
+
+	t := d.fn.Type()
+	var args []reflect.Value
+	if d.asserts {
+		args = append(args, reflect.ValueOf(assert))
+	}
+	for _, param := range d.re.FindStringSubmatch(step.Text)[1:] {
+		arg, err := stepArg(param, t.In(len(args)))
+		if err != nil {
+			panic(fmt.Sprintf("mspec: the step %q: %v", step.Text, err))
+		}
+		args = append(args, arg)
+	}
+	if len(args) < t.NumIn() {
+		switch t.In(len(args)) {
+		case tableType:
+			args = append(args, reflect.ValueOf(step.Table))
+		case docStringType:
+			doc := gherkin.DocString{}
+			if step.DocString != nil {
+				doc = *step.DocString
+			}
+			args = append(args, reflect.ValueOf(doc))
+		}
+	}
+	d.fn.Call(args)
+}
+
+// stepArg converts a parameter of a step to the type of its argument.
+func stepArg(param string, t reflect.Type) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(param)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(param)
+		if err != nil {
+			return v, err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(param, 10, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(param, 10, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(param, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetFloat(f)
+	}
+	return v, nil
+}
+
+// Features runs the scenarios of the Gherkin .feature files matching glob,
+// with the steps they are made of matched to the step definitions:
+//
+//	func Test_Features(t *testing.T) {
+//		var dog *Dog
+//		steps := &Steps{}
+//		steps.Before(func() { dog = nil })
+//		steps.Step(`a dog`, func() { dog = BirthDog() })
+//		...
+//
+//		Features(t, "testdata/*.feature", steps)
+//	}
+//
+// Each scenario runs as a Given, as if it had been written in Go: the
+// Given is named after the scenario and lists its Given steps, its When
+// steps are the Whens, nested in the order they are written, and each Then
+// step is an It of the step before it.  A Scenario Outline runs once for
+// each row of its examples, and the tags of a scenario are the labels of
+// its specs.  The Its of steps that match no step definition, or that
+// follow such a step, are NOT IMPLEMENTED.
+func Features(t *testing.T, glob string, steps *Steps) {
+	paths, err := filepath.Glob(glob)
+	if err != nil {
+		t.Fatalf("mspec: %v", err)
+	}
+	if len(paths) == 0 {
+		t.Errorf("mspec: no feature files match %s", glob)
+		return
+	}
+
+	for _, path := range paths {
+		feature, err := gherkin.ParseFile(path)
+		if err != nil {
+			t.Errorf("mspec: %v", err)
+			continue
+		}
+		subtest(t, feature.Name, func(t *testing.T) {
+			for _, scenario := range feature.Scenarios {
+				steps.scenario(t, feature, scenario)
+			}
+		})
+	}
+}
+
+// scenario runs the scenario of the feature as a Given, once for each row
+// of its examples if it is an outline.
+func (s *Steps) scenario(t *testing.T, feature *gherkin.Feature, scenario *gherkin.Scenario) {
+	var background []*gherkin.Step
+	if feature.Background != nil {
+		background = feature.Background.Steps
+	}
+	tags := append(feature.Tags[:len(feature.Tags):len(feature.Tags)], scenario.Tags...)
+
+	if !scenario.Outline() {
+		s.given(t, feature.Name, scenario, append(background, scenario.Steps...), tags, nil)
+		return
+	}
+	for _, examples := range scenario.Examples {
+		for _, cells := range examples.Rows {
+			row := Row{}
+			for i, name := range examples.Header {
+				row[name] = cells[i]
+			}
+			all := append(background[:len(background):len(background)], scenario.Steps...)
+			for i, step := range all {
+				all[i] = row.substituteStep(step)
+			}
+			s.given(t, feature.Name, scenario, all, append(tags[:len(tags):len(tags)], examples.Tags...), row)
+		}
+	}
+}
+
+// substituteStep returns step with the placeholders of the row replaced in
+// its text, data table and doc string.
+func (row Row) substituteStep(step *gherkin.Step) *gherkin.Step {
+	sub := *step
+	sub.Text = row.substitute(step.Text)
+	if step.Table != nil {
+		sub.Table = make(gherkin.Table, len(step.Table))
+		for i, cells := range step.Table {
+			sub.Table[i] = make([]string, len(cells))
+			for j, cell := range cells {
+				sub.Table[i][j] = row.substitute(cell)
+			}
+		}
+	}
+	if step.DocString != nil {
+		doc := *step.DocString
+		doc.Content = row.substitute(doc.Content)
+		sub.DocString = &doc
+	}
+	return &sub
+}
+
+// given runs the steps of a scenario as a Given named after the scenario,
+// with its Given steps listed beneath it in the output but not in the name
+// of its subtest.
+func (s *Steps) given(t *testing.T, feature string, scenario *gherkin.Scenario, steps []*gherkin.Step, tags []string, row Row) {
+	n := 0
+	for n < len(steps) && steps[n].Kind == "Given" {
+		n++
+	}
+	givens, rest := steps[:n], steps[n:]
+
+	name := row.substitute(scenario.Name)
+	given := name
+	for i, step := range givens {
+		switch {
+		case i == 0:
+			given += "\n  Given " + step.Text
+		case step.Keyword == "But":
+			given += "\n  But " + step.Text
+		default:
+			given += "\n  And " + step.Text
+		}
+	}
+	for _, tag := range tags {
+		given += " @" + tag
+	}
+
+	runGiven(t, feature, given, declaration{name: name, row: row}, []func(When){func(when When) {
+		for _, fn := range s.before {
+			fn()
+		}
+		implemented := s.run(givens)
+
+		// the Then steps before any When step are of the scenario itself
+		if len(rest) == 0 || rest[0].Kind == "Then" {
+			when(name, func(it It) {
+				s.steps(it, rest, implemented)
+			})
+			return
+		}
+		when(rest[0].Text, func(it It) {
+			s.steps(it, rest[1:], implemented && s.run(rest[:1]))
+		})
+	}})
+}
+
+// run runs the steps until one of them matches no definition, and reports
+// whether they all ran.
+func (s *Steps) run(steps []*gherkin.Step) bool {
+	for _, step := range steps {
+		def := s.match(step)
+		if def == nil {
+			return false
+		}
+		def.call(nil, step)
+	}
+	return true
+}
+
+// steps declares the steps that follow a When step, in the order they are
+// written: each Then step as an It of the step before it, and the next step
+// that is not a Then as a step nested in it, along with those after it.
+// The Its are NOT IMPLEMENTED unless they, and the steps before them, match
+// a definition.
+func (s *Steps) steps(it It, steps []*gherkin.Step, implemented bool) {
+	for i, step := range steps {
+		if step.Kind != "Then" {
+			nested := func(it It) {
+				s.steps(it, steps[i+1:], implemented && s.run(steps[i:i+1]))
+			}
+			switch step.Keyword {
+			case "But":
+				it.But(step.Text, nested)
+			case "When":
+				it.When(step.Text, nested)
+			default:
+				it.And(step.Text, nested)
+			}
+			return
+		}
+
+		def := s.match(step)
+		if def == nil || !implemented {
+			it(step.Text)
+			continue
+		}
+		step := step
+		it(step.Text, func(assert Assert) {
+			def.call(assert, step)
+		})
+	}
+}
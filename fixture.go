@@ -0,0 +1,160 @@
+package mspec
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Lifetime is how long a fixture lives once it is built.
+type Lifetime int
+
+const (
+	// PerSpec fixtures are built for each spec that requests them, and are
+	// torn down once the spec completes.
+	PerSpec Lifetime = iota
+
+	// PerGiven fixtures are built once for all the specs of a Given that
+	// request them, and are torn down once the Given completes.
+	PerGiven
+)
+
+// fixture is a fixture as it was defined, for any type.
+type fixture struct {
+	lifetime Lifetime
+	setup    func() interface{}
+	teardown func(interface{})
+}
+
+var (
+	fixturesMu sync.RWMutex
+	fixtures   = map[reflect.Type]fixture{}
+)
+
+// DefineFixture defines how to set up, and tear down, the fixture of type
+// T that the specs request with Let.  The teardown may be nil.  Defining a
+// fixture of the same type again replaces it.
+//
+//	func init() {
+//		DefineFixture(PerSpec, func() *Dog {
+//			return BirthDog()
+//		}, nil)
+//
+//		DefineFixture(PerGiven, func() *sql.DB {
+//			return openTestDB()
+//		}, func(db *sql.DB) {
+//			db.Close()
+//		})
+//	}
+func DefineFixture[T any](lifetime Lifetime, setup func() T, teardown func(T)) {
+	f := fixture{
+		lifetime: lifetime,
+		setup:    func() interface{} { return setup() },
+	}
+	if teardown != nil {
+		f.teardown = func(v interface{}) { teardown(v.(T)) }
+	}
+
+	fixturesMu.Lock()
+	defer fixturesMu.Unlock()
+	fixtures[typeOf[T]()] = f
+}
+
+// Let returns the fixture of type T, building it the first time it is
+// requested within its lifetime.  It takes the assert, or Specification, of
+// a spec, or the when or it handed to a closure:
+//
+//	it("should bark", func(assert Assert) {
+//		dog := Let[*Dog](assert)
+//		assert.Equal("woof", dog.Bark())
+//	})
+//
+// A PerSpec fixture requested by a Given or When closure lives as long as
+// that closure's scope, which in isolated mode runs again for every spec,
+// and its specs get that same fixture.
+//
+// Let panics if no fixture of type T was defined.
+func Let[T any](in interface{}) T {
+	typ := typeOf[T]()
+	fixturesMu.RLock()
+	f, ok := fixtures[typ]
+	fixturesMu.RUnlock()
+	if !ok {
+		panic(fmt.Sprintf("mspec: no fixture of type %v is defined, see DefineFixture", typ))
+	}
+
+	var spec *Specification
+	var sc *scope
+	switch in := in.(type) {
+	case *Specification:
+		spec = in
+	case When:
+		sc = in.scope()
+	case It:
+		sc = in.scope()
+	case Assert:
+		spec = SpecOf(in)
+	default:
+		panic(fmt.Sprintf("mspec: Let takes the assert of a spec, or the when or it handed to a closure, not %T", in))
+	}
+
+	if spec != nil {
+		if f.lifetime == PerGiven {
+			return spec.run.fixtures.get(typ, f, &spec.run.cleanups).(T)
+		}
+		if spec.fixtures == nil {
+			panic("mspec: Let can only be called while the spec runs")
+		}
+		return spec.fixtures.get(typ, f, spec.cleanups).(T)
+	}
+
+	if f.lifetime == PerGiven {
+		return sc.spec.run.fixtures.get(typ, f, &sc.spec.run.cleanups).(T)
+	}
+	return sc.fixtures.get(typ, f, &sc.cleanups).(T)
+}
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// fixtureSet memoizes the fixtures built for a spec, a scope or a Given.
+type fixtureSet struct {
+	mu     sync.Mutex
+	built  map[reflect.Type]interface{}
+	parent *fixtureSet // the fixtures of the scope it is nested in
+}
+
+// get returns the fixture of type typ, building it with f if neither s nor
+// the sets it is nested in built it yet, and adding its teardown to
+// cleanups.  The lock is not held while building it, as its setup may
+// request the fixtures it needs.
+func (s *fixtureSet) get(typ reflect.Type, f fixture, cleanups *cleanups) interface{} {
+	for set := s; set != nil; set = set.parent {
+		set.mu.Lock()
+		v, ok := set.built[typ]
+		set.mu.Unlock()
+		if ok {
+			return v
+		}
+	}
+
+	v := f.setup()
+	if f.teardown != nil {
+		cleanups.add(func() { f.teardown(v) })
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.built == nil {
+		s.built = map[reflect.Type]interface{}{}
+	}
+	s.built[typ] = v
+	return v
+}
+
+// tearDown tears down the PerGiven fixtures of the Given, failing it if any
+// of the teardowns panic.
+func (r *runContext) tearDown(given Specification) {
+	r.cleanups.run(given.PrintPanic)
+}
@@ -0,0 +1,63 @@
+package mspec
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// The command line flags of mspec.  Test binaries parse them along with
+// their own -test.* flags:
+//
+//	go test -mspec.focus='Given a dog.*It should be clean'
+//
+// Each flag may also be set with its environment variable, which is handy
+// when running many packages with go test ./...
+var (
+	focusFlag = flag.String("mspec.focus", "", "run only the specs whose \"Feature Given When It\" sentence matches `regexp` (or set MSPEC_FOCUS)")
+)
+
+// flagOrEnv returns the value of a flag, or of the environment variable when
+// the flag was not given.
+func flagOrEnv(value *string, env string) string {
+	if *value != "" {
+		return *value
+	}
+	return os.Getenv(env)
+}
+
+var (
+	filterOnce sync.Once
+	filter     *regexp.Regexp
+	filterErr  error
+)
+
+// specFilter returns the regexp given by -mspec.focus or MSPEC_FOCUS, or nil
+// when all specs run.  It fails when the regexp does not compile.
+var specFilter = func() (*regexp.Regexp, error) {
+	filterOnce.Do(func() {
+		expr := flagOrEnv(focusFlag, "MSPEC_FOCUS")
+		if expr == "" {
+			return
+		}
+		filter, filterErr = regexp.Compile(expr)
+		if filterErr != nil {
+			filterErr = fmt.Errorf("mspec: -mspec.focus: %v", filterErr)
+		}
+	})
+	return filter, filterErr
+}
+
+// sentence returns the spec as the single line "Feature Given When It"
+// sentence that -mspec.focus is matched against.
+func (spec *Specification) sentence() string {
+	return strings.Join(strings.Fields(strings.Join([]string{
+		spec.Feature,
+		"Given", spec.Given,
+		spec.whens(),
+		"It", spec.Spec,
+	}, " ")), " ")
+}
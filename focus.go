@@ -0,0 +1,108 @@
+package mspec
+
+import (
+	"flag"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/eduncan911/go-mspec/colors"
+)
+
+// notFocused is the reason given for the specs skipped while only the
+// focused specs run.
+const notFocused = "not focused"
+
+var focusedFlag = flag.Bool("mspec.focused", false, "run only the specs marked with Focus and skip the others (or set MSPEC_FOCUSED)")
+
+// FocusGiven is a Given that is focused.  When the tests are run with the
+// -mspec.focused flag, or the MSPEC_FOCUSED environment variable, only the
+// focused specs run and all of the others are skipped.
+//
+// Focusing is meant for iterating on a single scenario.  Focused specs fail
+// when the CI environment variable is set, so a focus never gets merged by
+// accident.
+func FocusGiven(t *testing.T, given string, when ...func(When)) {
+	runGiven(t, featureDesc(2), given, declaration{mark: mark{focus: true}}, when)
+}
+
+// SkipGiven is a Given that is skipped for the reason given, without having
+// to comment it out.  Its closures are never run.
+func SkipGiven(t *testing.T, given, reason string, when ...func(When)) {
+	runGiven(t, featureDesc(2), given, declaration{mark: mark{skip: true, reason: reason}}, when)
+}
+
+// Focus declares a When that is focused.  See FocusGiven.
+func (w When) Focus(when string, its ...func(It)) {
+	w.scope().declareStep("When", when, mark{focus: true}, its)
+}
+
+// Skip declares a When that is skipped for the reason given.  Its closures
+// are never run.
+func (w When) Skip(when, reason string, its ...func(It)) {
+	w.scope().declareStep("When", when, mark{skip: true, reason: reason}, its)
+}
+
+// Focus declares an It that is focused.  See FocusGiven.
+func (it It) Focus(title string, assert ...func(Assert)) {
+	it.scope().declareIt(title, mark{focus: true}, assert)
+}
+
+// Skip declares an It that is skipped for the reason given.  Its assertions
+// are never run.
+func (it It) Skip(title, reason string, assert ...func(Assert)) {
+	it.scope().declareIt(title, mark{skip: true, reason: reason}, assert)
+}
+
+// skipped is what Skip() panics with to stop the assertion that skipped its
+// spec.  It is recovered by the spec, which is then reported as skipped.
+type skipped struct {
+	reason string
+}
+
+// Skip stops the assertion and reports the spec as skipped for the reason
+// given, as t.Skip() does for a test.  The assertions of the spec after it
+// do not run, while the specs after it do.
+//
+//	if testing.Short() {
+//		SpecOf(assert).Skip("talks to the database")
+//	}
+func (spec *Specification) Skip(reason string) {
+	panic(skipped{reason})
+}
+
+// skip marks t as skipped when it is the spec's own subtest, so that go test
+// reports the spec as skipped.  It must be the last thing the subtest does.
+func skip(t *testing.T, reason string) {
+	if t == nil || t.Name() == "" {
+		return
+	}
+	t.Helper()
+	t.Skip(reason)
+}
+
+// focusMode reports whether only the focused specs run, as turned on by the
+// -mspec.focused flag or the MSPEC_FOCUSED environment variable.
+var focusMode = func() bool {
+	if *focusedFlag {
+		return true
+	}
+	focused, _ := strconv.ParseBool(os.Getenv("MSPEC_FOCUSED"))
+	return focused
+}
+
+// inCI reports whether the tests are running on a CI server, which all of
+// the popular ones signal with the CI environment variable.
+func inCI() bool {
+	return os.Getenv("CI") != ""
+}
+
+// failFocused fails a focused spec that ran on a CI server.
+func (spec *Specification) failFocused() {
+	if spec.T != nil {
+		spec.T.Fail()
+	}
+	spec.PrintSpecWithError()
+	spec.run.printf("%s        focused specs fail when running in CI; remove the Focus before merging%s\n\n",
+		spec.run.config.AnsiOfExpectedError, colors.Reset)
+}
@@ -0,0 +1,79 @@
+package mspec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eduncan911/go-mspec/colors"
+)
+
+// GivenFuzz is a Given for a fuzz test.  The seeds are added to the corpus
+// of f, and the Given runs once for every input of the corpus and every
+// input the fuzzer generates from it.  The <input> placeholder in the text
+// of the Given, and of its Whens and Its, is replaced by the input:
+//
+//	func Fuzz_Parser(f *testing.F) {
+//		GivenFuzz(f, "any input from this corpus", []string{"", "a=1", "a=1&b=2"},
+//			func(when When, input string) {
+//
+//				when("parsed", func(it It) {
+//					q, err := Parse(input)
+//
+//					it("should round-trip", func(assert Assert) {
+//						if err == nil {
+//							assert.Equal(input, q.String())
+//						}
+//					})
+//				})
+//			})
+//	}
+//
+// The Its of an input that passes are not printed, as there may be millions
+// of them.  When an input fails, or panics, the specs of that input are
+// logged to its test so that go test reports the failing input, as minimized
+// by the fuzzer, in the same format as any other failing spec.
+func GivenFuzz[In any](f *testing.F, given string, seeds []In, when ...func(When, In)) {
+	feature := featureDesc(2)
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, in In) {
+		row := Row{"input": fuzzInput(in)}
+
+		whens := make([]func(When), len(when))
+		for i, fn := range when {
+			fn := fn
+			whens[i] = func(w When) {
+				fn(w, in)
+			}
+		}
+		runGiven(t, feature, row.substitute(given), declaration{row: row, fuzz: true}, whens)
+	})
+}
+
+// fuzzInput renders a fuzzed input so that inputs that are not printable,
+// as the fuzzer often generates, can still be read and copied.
+func fuzzInput(in interface{}) string {
+	switch v := in.(type) {
+	case string:
+		return strconv.Quote(v)
+	case []byte:
+		return strconv.Quote(string(v))
+	}
+	return fmt.Sprintf("%#v", in)
+}
+
+// logFailure logs the output of a fuzzed input to its test if the input
+// failed.  The output of the fuzzing workers is discarded by go test, only
+// what is logged to the test of a failing input is reported.
+func (r *runContext) logFailure() {
+	defer r.out.Reset()
+	if !r.fuzz.Failed() {
+		return
+	}
+	out := strings.TrimRight(r.out.String(), "\n")
+	r.fuzz.Logf("\n%sFeature: %s%s\n%s", r.config.AnsiOfFeature, r.feature, colors.Reset, out)
+}
@@ -0,0 +1,382 @@
+// Package gherkin parses the Gherkin .feature files that product owners
+// write, so that mspec can run their scenarios against Go step
+// definitions.
+//
+//	Feature: Washing dogs
+//
+//	  Background:
+//	    Given a dog
+//
+//	  @bath
+//	  Scenario: A painted dog is washed
+//	    Given the dog is painted "red"
+//	    When the dog is washed 2 times
+//	    Then the dog should be "brown"
+//
+//	  Scenario Outline: Dogs of all colors
+//	    Given the dog is painted "<color>"
+//	    When the dog is washed <times> times
+//	    Then the dog should be "brown"
+//
+//	    Examples:
+//	      | color | times |
+//	      | red   | 1     |
+//	      | blue  | 2     |
+//
+// Data tables and doc strings are attached to the step before them.  The
+// keywords are read in English only.
+package gherkin
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Feature is a parsed .feature file.
+type Feature struct {
+	Name        string
+	Description string
+	Tags        []string
+	Background  *Scenario // nil when there is no Background
+	Scenarios   []*Scenario
+	Path        string // the file the Feature was parsed from, if any
+}
+
+// Scenario is a Scenario, a Scenario Outline or the Background of a
+// Feature.
+type Scenario struct {
+	Keyword  string // as written, such as "Scenario" or "Scenario Outline"
+	Name     string
+	Tags     []string
+	Steps    []*Step
+	Examples []*Examples // the examples of a Scenario Outline
+	Line     int
+}
+
+// Outline reports whether the scenario is a Scenario Outline, which runs
+// once for each row of its examples.
+func (sc *Scenario) Outline() bool {
+	return sc.Keyword == "Scenario Outline" || sc.Keyword == "Scenario Template"
+}
+
+// Step is a step of a scenario.
+type Step struct {
+	Keyword   string // as written: Given, When, Then, And, But or *
+	Kind      string // Given, When or Then; what an And, But or * continues
+	Text      string
+	Table     Table      // the data table of the step, if any
+	DocString *DocString // the doc string of the step, if any
+	Line      int
+}
+
+// Table is a data table.  The first row is usually its header.
+type Table [][]string
+
+// DocString is a doc string, the block of text between """ or ```.
+type DocString struct {
+	MediaType string // as written after the opening delimiter, if any
+	Content   string
+}
+
+// Examples are the examples of a Scenario Outline.
+type Examples struct {
+	Name   string
+	Tags   []string
+	Header []string
+	Rows   [][]string
+}
+
+// ParseFile parses the .feature file at path.
+func ParseFile(path string) (*Feature, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	feature, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%v", path, err)
+	}
+	feature.Path = path
+	return feature, nil
+}
+
+// Parse parses a feature written in Gherkin.
+func Parse(r io.Reader) (*Feature, error) {
+	p := &parser{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		p.line++
+		if err := p.parse(scanner.Text()); err != nil {
+			return nil, fmt.Errorf("%d: %v", p.line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if p.doc != nil {
+		return nil, fmt.Errorf("%d: the doc string opened on line %d is not closed", p.line, p.docLine)
+	}
+	if p.feature == nil {
+		return nil, fmt.Errorf("%d: there is no Feature", p.line)
+	}
+	return p.feature, nil
+}
+
+// parser is the state of parsing a feature, one line at a time.
+type parser struct {
+	line     int
+	feature  *Feature
+	scenario *Scenario // the scenario, or Background, being parsed
+	examples *Examples // the examples being parsed
+	step     *Step     // the step the tables and doc strings belong to
+	tags     []string  // the tags for what is declared next
+
+	// description collects the free text after the Feature line.
+	description []string
+	inFeature   bool
+
+	// doc collects the lines of a doc string until it is closed.
+	doc       []string
+	docFence  string
+	docIndent int
+	docLine   int
+}
+
+func (p *parser) parse(raw string) error {
+	if p.doc != nil {
+		return p.parseDocString(raw)
+	}
+
+	line := strings.TrimSpace(raw)
+	switch {
+	case line == "" || strings.HasPrefix(line, "#"):
+		return nil
+
+	case strings.HasPrefix(line, "@"):
+		for _, tag := range strings.Fields(line) {
+			if strings.HasPrefix(tag, "#") {
+				break
+			}
+			p.tags = append(p.tags, strings.TrimPrefix(tag, "@"))
+		}
+		return nil
+
+	case strings.HasPrefix(line, "|"):
+		return p.parseRow(line)
+
+	case strings.HasPrefix(line, `"""`) || strings.HasPrefix(line, "```"):
+		if p.step == nil {
+			return fmt.Errorf("a doc string must follow a step")
+		}
+		if p.step.DocString != nil {
+			return fmt.Errorf("the step already has a doc string")
+		}
+		p.docFence = line[:3]
+		p.docIndent = len(raw) - len(strings.TrimLeft(raw, " \t"))
+		p.docLine = p.line
+		p.doc = []string{}
+		p.step.DocString = &DocString{MediaType: strings.TrimSpace(line[3:])}
+		return nil
+	}
+
+	if keyword, text, ok := header(line); ok {
+		return p.parseHeader(keyword, text)
+	}
+	if keyword, text, ok := stepOf(line); ok {
+		return p.parseStep(keyword, text)
+	}
+
+	// free text describes the Feature, or what follows it
+	if p.inFeature {
+		p.description = append(p.description, line)
+		return nil
+	}
+	if p.feature == nil || p.scenario != nil && len(p.scenario.Steps) > 0 && p.examples == nil {
+		return fmt.Errorf("unexpected %q", line)
+	}
+	return nil
+}
+
+// headers are the keywords of the sections of a feature, longest first.
+var headers = []string{
+	"Scenario Outline", "Scenario Template", "Background", "Scenario",
+	"Example", "Examples", "Scenarios", "Feature", "Rule",
+}
+
+func header(line string) (keyword, text string, ok bool) {
+	for _, h := range headers {
+		if strings.HasPrefix(line, h+":") {
+			return h, strings.TrimSpace(line[len(h)+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+func (p *parser) parseHeader(keyword, text string) error {
+	tags := p.tags
+	p.tags = nil
+	p.endFeatureDescription()
+
+	if keyword == "Feature" {
+		if p.feature != nil {
+			return fmt.Errorf("there can be only one Feature in a file")
+		}
+		p.feature = &Feature{Name: text, Tags: tags}
+		p.inFeature = true
+		return nil
+	}
+	if p.feature == nil {
+		return fmt.Errorf("%s: must follow a Feature", keyword)
+	}
+
+	p.step = nil
+	p.examples = nil
+	switch keyword {
+	case "Rule":
+		// the scenarios of a rule are the scenarios of the feature
+		p.scenario = nil
+
+	case "Background":
+		if p.feature.Background != nil {
+			return fmt.Errorf("there can be only one Background")
+		}
+		p.scenario = &Scenario{Keyword: keyword, Name: text, Line: p.line}
+		p.feature.Background = p.scenario
+
+	case "Examples", "Scenarios":
+		if p.scenario == nil || !p.scenario.Outline() {
+			return fmt.Errorf("%s: must follow a Scenario Outline", keyword)
+		}
+		p.examples = &Examples{Name: text, Tags: tags}
+		p.scenario.Examples = append(p.scenario.Examples, p.examples)
+
+	default:
+		p.scenario = &Scenario{Keyword: keyword, Name: text, Tags: tags, Line: p.line}
+		p.feature.Scenarios = append(p.feature.Scenarios, p.scenario)
+	}
+	return nil
+}
+
+func (p *parser) endFeatureDescription() {
+	if !p.inFeature {
+		return
+	}
+	p.feature.Description = strings.Join(p.description, "\n")
+	p.description = nil
+	p.inFeature = false
+}
+
+// keywords are the keywords of steps.
+var keywords = []string{"Given", "When", "Then", "And", "But", "*"}
+
+func stepOf(line string) (keyword, text string, ok bool) {
+	for _, k := range keywords {
+		if strings.HasPrefix(line, k+" ") {
+			return k, strings.TrimSpace(line[len(k):]), true
+		}
+	}
+	return "", "", false
+}
+
+func (p *parser) parseStep(keyword, text string) error {
+	p.endFeatureDescription()
+	if p.scenario == nil || p.examples != nil {
+		return fmt.Errorf("the step %q must be in a Scenario or Background", text)
+	}
+
+	kind := keyword
+	switch keyword {
+	case "And", "But", "*":
+		kind = "Given"
+		if n := len(p.scenario.Steps); n > 0 {
+			kind = p.scenario.Steps[n-1].Kind
+		}
+	}
+	p.step = &Step{Keyword: keyword, Kind: kind, Text: text, Line: p.line}
+	p.scenario.Steps = append(p.scenario.Steps, p.step)
+	return nil
+}
+
+func (p *parser) parseRow(line string) error {
+	cells, err := cellsOf(line)
+	if err != nil {
+		return err
+	}
+	switch {
+	case p.examples != nil:
+		if p.examples.Header == nil {
+			p.examples.Header = cells
+			return nil
+		}
+		if len(cells) != len(p.examples.Header) {
+			return fmt.Errorf("the row has %d cells, not the %d of its header", len(cells), len(p.examples.Header))
+		}
+		p.examples.Rows = append(p.examples.Rows, cells)
+
+	case p.step != nil:
+		if n := len(p.step.Table); n > 0 && len(cells) != len(p.step.Table[0]) {
+			return fmt.Errorf("the row has %d cells, not the %d of the row before it", len(cells), len(p.step.Table[0]))
+		}
+		p.step.Table = append(p.step.Table, cells)
+
+	default:
+		return fmt.Errorf("a table must follow a step or Examples")
+	}
+	return nil
+}
+
+// cellsOf splits a row of a table into its cells.  A | in a cell is escaped
+// as \|, a \ as \\ and a newline as \n.
+func cellsOf(line string) ([]string, error) {
+	if !strings.HasSuffix(line, "|") || len(line) < 2 {
+		return nil, fmt.Errorf("the row %q must end with |", line)
+	}
+	var cells []string
+	var cell strings.Builder
+	for i := 1; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && i+1 < len(line):
+			i++
+			switch line[i] {
+			case 'n':
+				cell.WriteByte('\n')
+			case '|', '\\':
+				cell.WriteByte(line[i])
+			default:
+				cell.WriteByte('\\')
+				cell.WriteByte(line[i])
+			}
+		case c == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(c)
+		}
+	}
+	return cells, nil
+}
+
+func (p *parser) parseDocString(raw string) error {
+	if strings.TrimSpace(raw) == p.docFence {
+		p.step.DocString.Content = strings.Join(p.doc, "\n")
+		p.doc = nil
+		return nil
+	}
+	// the indentation of the opening delimiter is not part of the content
+	i := 0
+	for i < p.docIndent && i < len(raw) && (raw[i] == ' ' || raw[i] == '\t') {
+		i++
+	}
+	line := raw[i:]
+	// an escaped delimiter is part of the content
+	line = strings.Replace(line, `\"\"\"`, `"""`, -1)
+	line = strings.Replace(line, "\\`\\`\\`", "```", -1)
+	p.doc = append(p.doc, line)
+	return nil
+}
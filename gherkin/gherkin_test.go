@@ -0,0 +1,114 @@
+package gherkin
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const washing = `# a comment
+@pets
+Feature: Washing dogs
+  Dogs get dirty,
+  and need washing.
+
+  Background:
+    Given a dog
+    And a bath
+
+  @bath @quick
+  Scenario: A painted dog is washed
+    Given the dog is painted "red"
+    When the dog is washed 2 times
+    But it rolls in mud
+    Then the dog should be "brown"
+    And the dog should smell:
+      """text
+      like a "clean" dog
+        and soap
+      """
+
+  Scenario Outline: Dogs of all colors
+    Given the dogs:
+      | name | color   |
+      | Rex  | <color> |
+      | Bo   | a \| b  |
+    When the dogs are washed <times> times
+    Then they should be clean
+
+    Examples: colors
+      | color | times |
+      | red   | 1     |
+      | blue  | 2     |
+`
+
+func TestParse(t *testing.T) {
+	f, err := Parse(strings.NewReader(washing))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.Name != "Washing dogs" || f.Description != "Dogs get dirty,\nand need washing." {
+		t.Errorf("the feature is %q: %q", f.Name, f.Description)
+	}
+	if !reflect.DeepEqual(f.Tags, []string{"pets"}) {
+		t.Errorf("the feature is tagged %v", f.Tags)
+	}
+	if f.Background == nil || len(f.Background.Steps) != 2 || f.Background.Steps[1].Kind != "Given" {
+		t.Fatalf("the background is %+v", f.Background)
+	}
+	if len(f.Scenarios) != 2 {
+		t.Fatalf("there are %d scenarios", len(f.Scenarios))
+	}
+
+	sc := f.Scenarios[0]
+	if sc.Name != "A painted dog is washed" || sc.Outline() || !reflect.DeepEqual(sc.Tags, []string{"bath", "quick"}) {
+		t.Errorf("the scenario is %+v", sc)
+	}
+	var kinds []string
+	for _, step := range sc.Steps {
+		kinds = append(kinds, step.Keyword+"/"+step.Kind)
+	}
+	if expected := []string{"Given/Given", "When/When", "But/When", "Then/Then", "And/Then"}; !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("the steps are %v", kinds)
+	}
+	if sc.Steps[0].Text != `the dog is painted "red"` || sc.Steps[0].Line != 13 {
+		t.Errorf("the first step is %+v", sc.Steps[0])
+	}
+	doc := sc.Steps[4].DocString
+	if doc == nil || doc.MediaType != "text" || doc.Content != "like a \"clean\" dog\n  and soap" {
+		t.Errorf("the doc string is %+v", doc)
+	}
+
+	outline := f.Scenarios[1]
+	if !outline.Outline() || len(outline.Examples) != 1 {
+		t.Fatalf("the outline is %+v", outline)
+	}
+	table := Table{{"name", "color"}, {"Rex", "<color>"}, {"Bo", "a | b"}}
+	if !reflect.DeepEqual(outline.Steps[0].Table, table) {
+		t.Errorf("the table is %q", outline.Steps[0].Table)
+	}
+	examples := outline.Examples[0]
+	if examples.Name != "colors" || !reflect.DeepEqual(examples.Header, []string{"color", "times"}) ||
+		!reflect.DeepEqual(examples.Rows, [][]string{{"red", "1"}, {"blue", "2"}}) {
+		t.Errorf("the examples are %+v", examples)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, c := range []struct {
+		feature, err string
+	}{
+		{"Scenario: no feature", "1: Scenario: must follow a Feature"},
+		{"Feature: a\nScenario: b\nGiven c\n\"\"\"\nd", `5: the doc string opened on line 4 is not closed`},
+		{"Feature: a\n| b |", "2: a table must follow a step or Examples"},
+		{"Feature: a\nScenario Outline: b\nGiven <c>\nExamples:\n| c |\n| d | e |", "6: the row has 2 cells, not the 1 of its header"},
+		{"Feature: a\nScenario: b\nGiven c\nthis is not a step", `4: unexpected "this is not a step"`},
+		{"# nothing", "1: there is no Feature"},
+	} {
+		_, err := Parse(strings.NewReader(c.feature))
+		if err == nil || err.Error() != c.err {
+			t.Errorf("parsing %q failed with %v, not %s", c.feature, err, c.err)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package mspec
+
+// hooks are the lifecycle funcs registered in a Given or a When.  They apply
+// to every spec nested in it.
+type hooks struct {
+	beforeEach []func()
+	afterEach  []func()
+	beforeAll  []func()
+	afterAll   []func()
+}
+
+// BeforeEach registers fn to run before each spec of the Given.
+//
+//	Given(t, "a database", func(when When) {
+//		when.BeforeEach(func() { db.Begin() })
+//		when.AfterEach(func() { db.Rollback() })
+//		...
+func (w When) BeforeEach(fn func()) {
+	sc := w.scope()
+	sc.hooks.beforeEach = append(sc.hooks.beforeEach, fn)
+}
+
+// AfterEach registers fn to run after each spec of the Given, even when the
+// spec failed or panicked.
+func (w When) AfterEach(fn func()) {
+	sc := w.scope()
+	sc.hooks.afterEach = append(sc.hooks.afterEach, fn)
+}
+
+// BeforeAll registers fn to run once, before the first spec of the Given.
+func (w When) BeforeAll(fn func()) {
+	sc := w.scope()
+	sc.hooks.beforeAll = append(sc.hooks.beforeAll, fn)
+}
+
+// AfterAll registers fn to run once, after the last spec of the Given, even
+// when a spec failed or panicked.
+func (w When) AfterAll(fn func()) {
+	sc := w.scope()
+	sc.hooks.afterAll = append(sc.hooks.afterAll, fn)
+}
+
+// BeforeEach registers fn to run before each spec of the When.
+func (it It) BeforeEach(fn func()) {
+	sc := it.scope()
+	sc.hooks.beforeEach = append(sc.hooks.beforeEach, fn)
+}
+
+// AfterEach registers fn to run after each spec of the When, even when the
+// spec failed or panicked.
+func (it It) AfterEach(fn func()) {
+	sc := it.scope()
+	sc.hooks.afterEach = append(sc.hooks.afterEach, fn)
+}
+
+// BeforeAll registers fn to run once, before the first spec of the When.
+func (it It) BeforeAll(fn func()) {
+	sc := it.scope()
+	sc.hooks.beforeAll = append(sc.hooks.beforeAll, fn)
+}
+
+// AfterAll registers fn to run once, after the last spec of the When, even
+// when a spec failed or panicked.
+func (it It) AfterAll(fn func()) {
+	sc := it.scope()
+	sc.hooks.afterAll = append(sc.hooks.afterAll, fn)
+}
+
+// beforeAll runs the BeforeAll hooks of sc and the scopes it is nested in
+// that have not run any spec yet, outermost first.  Whether they ran is kept
+// by the run context, as each pass of an isolated run has new scopes.
+func (sc *scope) beforeAll() {
+	if sc == nil {
+		return
+	}
+	sc.parent.beforeAll()
+	if sc.target == nil && sc.spec.run.config.isolated {
+		// the Given that the passes are nested in runs no spec of its own
+		return
+	}
+	ran := sc.spec.run.ranAll
+	if ran[sc.key()] {
+		return
+	}
+	ran[sc.key()] = true
+	for _, fn := range sc.hooks.beforeAll {
+		fn()
+	}
+}
+
+// beforeEach runs the BeforeEach hooks of sc and the scopes it is nested in,
+// outermost first.
+func (sc *scope) beforeEach() {
+	if sc == nil {
+		return
+	}
+	sc.parent.beforeEach()
+	for _, fn := range sc.hooks.beforeEach {
+		fn()
+	}
+}
+
+// afterEach runs the AfterEach hooks of sc and the scopes it is nested in,
+// innermost and last registered first.  Every hook runs even if one before
+// it panics.
+func (sc *scope) afterEach() {
+	if sc == nil {
+		return
+	}
+	defer sc.parent.afterEach()
+	runReversed(sc.hooks.afterEach)
+}
+
+// finish runs the AfterAll hooks of sc once its closure has returned, if any
+// of its specs ran and none are left to run, and then its cleanups.
+func (sc *scope) finish() {
+	defer sc.cleanUp()
+	if sc.plan != nil || !sc.lastPass() || !sc.spec.run.ranAll[sc.key()] {
+		return
+	}
+	runReversed(sc.hooks.afterAll)
+}
+
+// lastPass reports whether none of the specs of sc are left to run.  A
+// scope that is not part of an isolated pass runs all its specs at once,
+// while in isolated mode it is the pass of the last of them.
+func (sc *scope) lastPass() bool {
+	if sc.target == nil {
+		return true
+	}
+	passes := sc.spec.run.passes
+	passes[sc.key()]--
+	return passes[sc.key()] <= 0
+}
+
+// countPass counts the pass an isolated run makes through sc, and the
+// scopes it is nested in, for a spec found while discovering the specs.
+func (sc *scope) countPass() {
+	for ; sc != nil && sc.plan != nil; sc = sc.parent {
+		sc.spec.run.passes[sc.key()]++
+	}
+}
+
+// runReversed runs fns last to first, running them all even if one panics.
+func runReversed(fns []func()) {
+	for i := range fns {
+		defer fns[i]()
+	}
+}
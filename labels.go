@@ -0,0 +1,230 @@
+package mspec
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/eduncan911/go-mspec/colors"
+)
+
+var labelsFlag = flag.String("mspec.labels", "", "run only the specs whose labels match `expr`, such as '!slow && db' (or set MSPEC_LABELS)")
+
+// splitLabels splits the trailing @labels off the text of a Given, When or
+// It:
+//
+//	it("should sync the accounts @slow @db", ...)
+func splitLabels(text string) (string, []string) {
+	fields := strings.Fields(text)
+	i := len(fields)
+	for i > 0 && isLabel(fields[i-1]) {
+		i--
+	}
+	if i == len(fields) {
+		return text, nil
+	}
+	labels := make([]string, 0, len(fields)-i)
+	for _, label := range fields[i:] {
+		labels = append(labels, label[1:])
+	}
+	for j := len(fields) - 1; j >= i; j-- {
+		text = strings.TrimSuffix(strings.TrimRightFunc(text, unicode.IsSpace), fields[j])
+	}
+	return strings.TrimRightFunc(text, unicode.IsSpace), labels
+}
+
+func isLabel(field string) bool {
+	return len(field) > 1 && field[0] == '@'
+}
+
+// labels returns the labels of the spec, including those inherited from its
+// Given and Whens.
+func (spec *Specification) labels() []string {
+	texts := append([]string{spec.Given}, spec.steps...)
+	if len(spec.steps) == 0 {
+		texts = append(texts, spec.When)
+	}
+	texts = append(texts, spec.Spec)
+
+	var labels []string
+	for _, text := range texts {
+		_, l := splitLabels(text)
+		labels = append(labels, l...)
+	}
+	return labels
+}
+
+// label renders the labels of text dimmed, and then carries on in the ansi
+// color of the rest of the line.
+func (spec *Specification) label(text, ansi string) string {
+	text, labels := splitLabels(text)
+	if len(labels) == 0 {
+		return text
+	}
+	return fmt.Sprintf("%s %s%s@%s%s%s", text, colors.Reset, spec.run.config.AnsiOfLabels, strings.Join(labels, " @"), colors.Reset, ansi)
+}
+
+// labelExpr is a compiled -mspec.labels expression.
+type labelExpr func(labels map[string]bool) bool
+
+// match reports whether the labels match the expression.
+func (e labelExpr) match(labels []string) bool {
+	set := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		set[label] = true
+	}
+	return e(set)
+}
+
+var (
+	labelsOnce sync.Once
+	labelsExpr labelExpr
+	labelsErr  error
+)
+
+// labelFilter returns the expression given by -mspec.labels or
+// MSPEC_LABELS, or nil when all specs run.  It fails when the expression
+// does not parse.
+var labelFilter = func() (labelExpr, error) {
+	labelsOnce.Do(func() {
+		expr := flagOrEnv(labelsFlag, "MSPEC_LABELS")
+		if expr == "" {
+			return
+		}
+		labelsExpr, labelsErr = parseLabels(expr)
+		if labelsErr != nil {
+			labelsErr = fmt.Errorf("mspec: -mspec.labels: %v", labelsErr)
+		}
+	})
+	return labelsExpr, labelsErr
+}
+
+// parseLabels compiles a label expression.  Labels are combined with !, &&
+// and || and grouped with parentheses:
+//
+//	db && !(slow || flaky)
+func parseLabels(expr string) (labelExpr, error) {
+	p := &labelParser{tokens: tokenizeLabels(expr)}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in %q", p.tokens[p.pos], expr)
+	}
+	return e, nil
+}
+
+func tokenizeLabels(expr string) []string {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		switch c := expr[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case strings.HasPrefix(expr[i:], "&&") || strings.HasPrefix(expr[i:], "||"):
+			tokens = append(tokens, expr[i:i+2])
+			i += 2
+		case c == '!' || c == '(' || c == ')':
+			tokens = append(tokens, expr[i:i+1])
+			i++
+		default:
+			j := i
+			for j < len(expr) && !strings.ContainsRune(" \t!()&|", rune(expr[j])) {
+				j++
+			}
+			if j == i {
+				j++
+			}
+			tokens = append(tokens, expr[i:j])
+			i = j
+		}
+	}
+	return tokens
+}
+
+// labelParser is a recursive descent parser of label expressions.
+type labelParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *labelParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *labelParser) or() (labelExpr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "||" {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(labels map[string]bool) bool { return l(labels) || right(labels) }
+	}
+	return left, nil
+}
+
+func (p *labelParser) and() (labelExpr, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "&&" {
+		p.pos++
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(labels map[string]bool) bool { return l(labels) && right(labels) }
+	}
+	return left, nil
+}
+
+func (p *labelParser) not() (labelExpr, error) {
+	switch token := p.peek(); token {
+	case "!":
+		p.pos++
+		e, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return func(labels map[string]bool) bool { return !e(labels) }, nil
+
+	case "(":
+		p.pos++
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return e, nil
+
+	case "":
+		return nil, fmt.Errorf("missing a label at the end")
+
+	case ")", "&&", "||":
+		return nil, fmt.Errorf("unexpected %q", token)
+
+	default:
+		if strings.ContainsAny(token, "&|") {
+			return nil, fmt.Errorf("unexpected %q", token)
+		}
+		p.pos++
+		label := strings.TrimPrefix(token, "@")
+		return func(labels map[string]bool) bool { return labels[label] }, nil
+	}
+}
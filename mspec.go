@@ -2,32 +2,40 @@ package mspec
 
 import (
 	"strings"
+	"sync"
 
 	"github.com/eduncan911/go-mspec/colors"
 )
 
-var config *MSpecConfig
+var (
+	// configMu guards config.  Each Given takes a snapshot of the config
+	// when it starts, so the config is never read while specs execute.
+	configMu sync.RWMutex
+	config   *MSpecConfig
+)
 
 // MSpecConfig defines the configuration used by the package.
 type MSpecConfig struct {
-	output outputType
+	output   outputType
+	isolated bool
+	strict   bool
+	random   bool
+	failFast bool
 
 	AnsiOfFeature            string
 	AnsiOfGiven              string
 	AnsiOfWhen               string
 	AnsiOfThen               string
 	AnsiOfThenNotImplemented string
+	AnsiOfThenFocused        string
+	AnsiOfThenSkipped        string
 	AnsiOfThenWithError      string
 	AnsiOfCode               string
 	AnsiOfCodeError          string
 	AnsiOfExpectedError      string
+	AnsiOfLabels             string
 
 	assertFn func(*Specification) Assert
-
-	lastFeature string
-	lastGiven   string
-	lastWhen    string
-	lastSpec    string
 }
 
 func init() {
@@ -49,34 +57,68 @@ func init() {
 //	    return &MyCustomAssertions{}
 //    })
 func AssertionsFn(fn func(s *Specification) Assert) {
+	configMu.Lock()
+	defer configMu.Unlock()
 	config.assertFn = fn
 }
 
 // SetConfig takes a Config instance and will be used for all tests
-// until ResetConfig() is called.
+// until ResetConfig() is called.  The modes turned on by SetIsolated,
+// SetStrict, SetRandomOrder and SetFailFast are kept.
 //
 //    mspec.SetConfig(Config{
 //      AnsiOfFeature: "",	// remove color coding for Feature
 //    })
 //
 func SetConfig(c MSpecConfig) {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	// keep the registered assertions and output unless they were copied over
+	if c.assertFn == nil {
+		c.assertFn = config.assertFn
+	}
+	if c.output == 0 {
+		c.output = config.output
+	}
+
+	// the modes cannot be set outside of the package but by their own funcs
+	c.isolated = config.isolated
+	c.strict = config.strict
+	c.random = config.random
+	c.failFast = config.failFast
 	config = &c
 }
 
 // ResetConfig will reset all options back to their default configuration.
 // Useful for custom colors in the middle of a specification.
 func ResetConfig() {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	// the registered assertions are not an option, so they survive a reset
+	var assertFn func(*Specification) Assert
+	if config != nil {
+		assertFn = config.assertFn
+	}
+
 	// setup a default configuration
 	config = &MSpecConfig{
+		output:   outputStdout,
+		assertFn: assertFn,
+
 		AnsiOfFeature:            strings.Join([]string{colors.White}, ""),
 		AnsiOfGiven:              strings.Join([]string{colors.Grey}, ""),
 		AnsiOfWhen:               strings.Join([]string{colors.LightGreen}, ""),
 		AnsiOfThen:               strings.Join([]string{colors.Green}, ""),
 		AnsiOfThenNotImplemented: strings.Join([]string{colors.LightYellow}, ""),
+		AnsiOfThenFocused:        strings.Join([]string{colors.LightMagenta}, ""),
+		AnsiOfThenSkipped:        strings.Join([]string{colors.Cyan}, ""),
 		AnsiOfThenWithError:      strings.Join([]string{colors.RegBg, colors.White, colors.Bold}, ""),
 		AnsiOfCode:               strings.Join([]string{colors.Grey}, ""),
 		AnsiOfCodeError:          strings.Join([]string{colors.White, colors.Bold}, ""),
 		AnsiOfExpectedError:      strings.Join([]string{colors.Red}, ""),
+		AnsiOfLabels:             strings.Join([]string{colors.Dim}, ""),
 	}
 }
 
@@ -84,6 +126,8 @@ func ResetConfig() {
 // Do not use this at this time.  The package API
 // will most likely change.
 func SetVerbose() {
+	configMu.Lock()
+	defer configMu.Unlock()
 	config.output = outputStdout
 }
 
@@ -91,9 +135,28 @@ func SetVerbose() {
 // Do not use this at this time.  The package API
 // will most likely change.
 func SetSilent() {
+	configMu.Lock()
+	defer configMu.Unlock()
 	config.output = outputNone
 }
 
+// SetIsolated runs the Given and When closures again from scratch for every
+// It, so each spec sees a fresh context no matter what the specs before it
+// did to theirs.
+func SetIsolated() {
+	configMu.Lock()
+	defer configMu.Unlock()
+	config.isolated = true
+}
+
+// SetShared runs the Given and When closures just once, sharing the context
+// they set up across all of their specs (default).
+func SetShared() {
+	configMu.Lock()
+	defer configMu.Unlock()
+	config.isolated = false
+}
+
 type outputType int
 
 const (
@@ -103,8 +166,9 @@ const (
 	outputHTML
 )
 
-func (c *MSpecConfig) resetLasts() {
-	c.lastGiven = ""
-	c.lastWhen = ""
-	c.lastSpec = ""
+// currentConfig returns a copy of the package configuration.
+func currentConfig() MSpecConfig {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return *config
 }
@@ -1,47 +1,2216 @@
 package mspec
 
 import (
+	"context"
+	"fmt"
 	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	asserts "github.com/eduncan911/go-mspec/assert"
+	"github.com/eduncan911/go-mspec/gherkin"
 )
 
-func Test_MSpec_Instances(t *testing.T) {
+func Test_MSpec_Instances(t *testing.T) {
+
+	SetSilent()
+
+	Given(t, "an mspec run context", func(when When) {
+
+		r := newRunContext()
+
+		when("the package config changes after the Given started", func(it It) {
+
+			SetVerbose()
+			SetSilent()
+			AssertionsFn(func(s *Specification) Assert {
+				return newAssertions(s)
+			})
+
+			it("should keep its own snapshot of the config", func(assert Assert) {
+				assert.Equal(outputNone, r.config.output)
+			})
+		})
+
+		when("the package config is set after the modes were turned on", func(it It) {
+
+			previous := currentConfig()
+			SetIsolated()
+			SetStrict()
+			SetRandomOrder()
+			SetFailFast()
+			SetConfig(MSpecConfig{AnsiOfFeature: ""})
+			c := currentConfig()
+			SetShared()
+			SetLenient()
+			SetDeclaredOrder()
+			SetContinueOnFailure()
+			SetConfig(previous)
+
+			it("should keep the modes", func(assert Assert) {
+				assert.True(c.isolated)
+				assert.True(c.strict)
+				assert.True(c.random)
+				assert.True(c.failFast)
+			})
+
+			it("should take the rest of the config", func(assert Assert) {
+				assert.Empty(c.AnsiOfFeature)
+				assert.Equal(outputNone, c.output)
+			})
+		})
+
+		when("rendering while the output is silent", func(it It) {
+
+			r.printf("%s", "some output")
+
+			it("should not buffer any output", func(assert Assert) {
+				assert.Equal(0, r.out.Len())
+			})
+		})
+
+		when("rendering to a verbose output", func(it It) {
+
+			v := &runContext{config: MSpecConfig{output: outputStdout}}
+			v.printf("%s", "some output")
+
+			it("should buffer the output until it is flushed", func(assert Assert) {
+				assert.Equal("some output", v.out.String())
+			})
+
+			it("should not share the last When or spec with other Givens", func(assert Assert) {
+				assert.Empty(v.lastWhen)
+				assert.Empty(v.lastSpec)
+			})
+		})
+	})
+}
+
+// customAssertions are custom assertions that only implement Assert.
+type customAssertions struct {
+	*asserts.Assertions
+}
+
+func Test_Custom_Assertions(t *testing.T) {
+
+	SetSilent()
+
+	Given(t, "custom assertions that only implement Assert", func(when When) {
+
+		var custom Assert
+		AssertionsFn(func(s *Specification) Assert {
+			return customAssertions{asserts.New(&mspectTestingT{spec: s})}
+		})
+		Given(&testing.T{}, "a Given", func(when When) {
+			when("the spec runs", func(it It) {
+				it("should assert with the custom assertions", func(assert Assert) {
+					custom = assert
+				})
+			})
+		})
+		AssertionsFn(func(s *Specification) Assert {
+			return newAssertions(s)
+		})
+
+		when("reaching the spec of the assertions", func(it It) {
+
+			spec := &Specification{}
+
+			it("should have asserted with them", func(assert Assert) {
+				assert.IsType(customAssertions{}, custom)
+			})
+
+			it("should return the spec of the default assertions", func(assert Assert) {
+				assert.True(SpecOf(newAssertions(spec)) == spec)
+			})
+
+			it("should panic for custom assertions that do not implement Specified", func(assert Assert) {
+				defer func() {
+					assert.NotNil(recover())
+				}()
+				SpecOf(custom)
+			})
+		})
+	})
+}
+
+func Test_Parallel_Givens(t *testing.T) {
+
+	// run with -race to catch any state shared between Givens.
+	for i := 0; i < 4; i++ {
+		t.Run(fmt.Sprintf("parallel %d", i), func(t *testing.T) {
+			t.Parallel()
+
+			Given(t, "a Given running in parallel with others", func(when When) {
+
+				count := 0
+
+				when("its When runs", func(it It) {
+
+					count++
+
+					it("should only see its own context", func(assert Assert) {
+						assert.Equal(1, count)
+					})
+
+					it("should render without interleaving with the other Givens")
+				})
+			})
+		})
+	}
+}
+
+func Test_Subtests(t *testing.T) {
+
+	Given(t, "a Given with a multi-line\ncontext", func(when When) {
+
+		when("building the name of its subtest", func(it It) {
+
+			name := subtestName("a Given with a multi-line\ncontext")
+
+			it("should flatten the text to a single line", func(assert Assert) {
+				assert.Equal("a Given with a multi-line context", name)
+			})
+		})
+
+		when("given a testing.T that cannot host subtests", func(it It) {
+
+			ran := false
+			subtest(&testing.T{}, "an inline spec", func(t *testing.T) {
+				ran = true
+			})
+
+			it("should run the spec inline", func(assert Assert) {
+				assert.True(ran)
+			})
+		})
+	})
+}
+
+func Test_Isolated_Context(t *testing.T) {
+
+	SetIsolated()
+	defer SetShared()
+
+	passes := 0
+
+	Given(t, "a context that is isolated for each spec", func(when When) {
+
+		passes++
+		count := 0
+
+		when("the context is mutated", func(it It) {
+
+			count++
+
+			it("should start from a fresh context", func(assert Assert) {
+				assert.Equal(1, count)
+				count = 100
+			})
+
+			it("should not see what the spec before it did", func(assert Assert) {
+				assert.Equal(1, count)
+			})
+		})
+
+		when("the context is mutated again", func(it It) {
+
+			count += 2
+
+			it("should not see the Whens before it", func(assert Assert) {
+				assert.Equal(2, count)
+			})
+		})
+	})
+
+	// one pass to discover the specs plus one pass per spec
+	if passes != 4 {
+		t.Errorf("expected the Given to run 4 times, ran %d times", passes)
+	}
+}
+
+func Test_Lifecycle_Hooks(t *testing.T) {
+
+	SetSilent()
+
+	Given(t, "hooks registered in a Given and in a When", func(when When) {
+
+		var calls []string
+		record := func(call string) func() {
+			return func() { calls = append(calls, call) }
+		}
+
+		failing := &testing.T{}
+		Given(failing, "a Given with hooks", func(when When) {
+
+			when.BeforeAll(record("given before all"))
+			when.BeforeEach(record("given before each"))
+			when.AfterEach(record("given after each"))
+			when.AfterAll(record("given after all"))
+
+			when("a When with hooks", func(it It) {
+
+				it.BeforeEach(record("when before each"))
+				it.AfterEach(record("when after each"))
+
+				it("should pass", func(assert Assert) {
+					record("spec")()
+				})
+
+				it("should fail", func(assert Assert) {
+					record("failing spec")()
+					assert.True(false)
+				})
+			})
+		})
+
+		when("the specs have run", func(it It) {
+
+			it("should run the hooks around each spec, even one that failed", func(assert Assert) {
+				assert.Equal([]string{
+					"given before all",
+					"given before each", "when before each",
+					"spec",
+					"when after each", "given after each",
+					"given before each", "when before each",
+					"failing spec",
+					"when after each", "given after each",
+					"given after all",
+				}, calls)
+			})
+
+			it("should still report the failing spec", func(assert Assert) {
+				assert.True(failing.Failed())
+			})
+		})
+
+		when("the specs run in isolated mode", func(it It) {
+
+			var calls []string
+			record := func(call string) func() {
+				return func() { calls = append(calls, call) }
+			}
+
+			SetIsolated()
+			Given(&testing.T{}, "an isolated Given with hooks", func(when When) {
+
+				when.BeforeAll(record("given before all"))
+				when.AfterAll(record("given after all"))
+
+				when("a When with hooks", func(it It) {
+
+					it.BeforeAll(record("when before all"))
+					it.AfterAll(record("when after all"))
+
+					it("should pass", func(assert Assert) { record("spec")() })
+					it("should pass too", func(assert Assert) { record("spec")() })
+					it("should pass again", func(assert Assert) { record("spec")() })
+				})
+
+				when("another When", func(it It) {
+					it.BeforeAll(record("other before all"))
+					it.AfterAll(record("other after all"))
+					it("should not be implemented")
+				})
+			})
+			SetShared()
+
+			it("should run the all hooks once, around all the passes", func(assert Assert) {
+				assert.Equal([]string{
+					"given before all",
+					"when before all",
+					"spec", "spec", "spec",
+					"when after all",
+					"other before all",
+					"other after all",
+					"given after all",
+				}, calls)
+			})
+		})
+
+		when("a behavior is included twice", func(it It) {
+
+			var shared, isolated []string
+			var calls *[]string
+			withHooks := DefineBehavior("a behavior with hooks", func(when When, name string) {
+				when("the behavior is run", func(it It) {
+					it.BeforeAll(func() { *calls = append(*calls, "before all "+name) })
+					it.AfterAll(func() { *calls = append(*calls, "after all "+name) })
+					it("should pass", func(assert Assert) {})
+				})
+			})
+
+			calls = &shared
+			Given(&testing.T{}, "a Given including it twice", withHooks.For("file"), withHooks.For("memory"))
+			calls = &isolated
+			SetIsolated()
+			Given(&testing.T{}, "an isolated Given including it twice", withHooks.For("file"), withHooks.For("memory"))
+			SetShared()
+
+			it("should run the all hooks of each of its Whens", func(assert Assert) {
+				expected := []string{
+					"before all file", "after all file",
+					"before all memory", "after all memory",
+				}
+				assert.Equal(expected, shared)
+				assert.Equal(expected, isolated)
+			})
+		})
+
+		when("a spec panics", func(it It) {
+
+			afterEach, afterAll := false, false
+			func() {
+				defer func() { recover() }()
+				Given(&testing.T{}, "a Given with after hooks", func(when When) {
+					when.AfterAll(func() { afterAll = true })
+					when("a When", func(it It) {
+						it.AfterEach(func() { afterEach = true })
+						it("should panic", func(assert Assert) {
+							panic("boom")
+						})
+					})
+				})
+			}()
+
+			it("should still run the AfterEach hooks", func(assert Assert) {
+				assert.True(afterEach)
+			})
+
+			it("should still run the AfterAll hooks", func(assert Assert) {
+				assert.True(afterAll)
+			})
+		})
+	})
+}
+
+func Test_Focus_And_Skip(t *testing.T) {
+
+	SetSilent()
+
+	Given(t, "specs that are focused or skipped", func(when When) {
+
+		var ran []string
+		record := func(spec string) func(Assert) {
+			return func(Assert) { ran = append(ran, spec) }
+		}
+
+		defer func(f func() bool) { focusMode = f }(focusMode)
+		focusMode = func() bool { return true }
+
+		Given(&testing.T{}, "a Given with focused specs", func(when When) {
+
+			when.Focus("a focused When", func(it It) {
+				it("should run every spec", record("focused When"))
+			})
+
+			when("an unfocused When", func(it It) {
+
+				it.Focus("should run a focused spec", record("focused It"))
+
+				it("should skip an unfocused spec", record("unfocused It"))
+				it.Skip("should skip a skipped spec", "a reason", record("skipped It"))
+			})
+
+			when.Skip("a skipped When", "a reason", func(it It) {
+				ran = append(ran, "skipped When")
+			})
+		})
+
+		SkipGiven(&testing.T{}, "a skipped Given", "a reason", func(when When) {
+			ran = append(ran, "skipped Given")
+		})
+
+		focusMode = func() bool { return false }
+
+		Given(&testing.T{}, "a Given without any focused specs", func(when When) {
+			when("a When", func(it It) {
+				it("should run", record("unfocused run"))
+			})
+		})
+
+		when("the specs have run", func(it It) {
+
+			it("should only have run the focused specs, or all specs when none are focused", func(assert Assert) {
+				assert.Equal([]string{"focused When", "focused It", "unfocused run"}, ran)
+			})
+		})
+	})
+
+	Given(t, "a focused spec running in CI", func(when When) {
+
+		t.Setenv("CI", "true")
+		defer func(f func() bool) { focusMode = f }(focusMode)
+		focusMode = func() bool { return true }
+
+		failing := &testing.T{}
+		FocusGiven(failing, "a focused Given", func(when When) {
+			when("a When", func(it It) {
+				it("should pass", func(assert Assert) {})
+			})
+		})
+
+		when("the spec has run", func(it It) {
+
+			it("should fail so the focus does not get merged", func(assert Assert) {
+				assert.True(failing.Failed())
+			})
+		})
+	})
+
+	Given(t, "MSPEC_FOCUSED set in the environment", func(when When) {
+
+		t.Setenv("MSPEC_FOCUSED", "true")
+		on := focusMode()
+		t.Setenv("MSPEC_FOCUSED", "false")
+		off := focusMode()
+
+		when("checking whether only the focused specs run", func(it It) {
+
+			it("should run only the focused specs when it is true", func(assert Assert) {
+				assert.True(on)
+			})
+
+			it("should run every spec when it is false", func(assert Assert) {
+				assert.False(off)
+			})
+		})
+	})
+}
+
+func Test_Spec_Filter(t *testing.T) {
+
+	SetSilent()
+
+	Given(t, "a filter set by -mspec.focus", func(when When) {
+
+		var ran []string
+		record := func(spec string) func(Assert) {
+			return func(Assert) { ran = append(ran, spec) }
+		}
+
+		defer func(f func() (*regexp.Regexp, error)) { specFilter = f }(specFilter)
+		specFilter = func() (*regexp.Regexp, error) {
+			return regexp.MustCompile("Given a dog.* When washed It should be clean"), nil
+		}
+
+		Given(&testing.T{}, "a dog\nthat is dirty", func(when When) {
+			when("washed", func(it It) {
+				it("should be clean", record("clean"))
+				it("should be wet", record("wet"))
+			})
+			when("walked", func(it It) {
+				it("should be clean", record("walked"))
+			})
+		})
+
+		when("the specs have run", func(it It) {
+
+			it("should only run the specs whose sentence matches", func(assert Assert) {
+				assert.Equal([]string{"clean"}, ran)
+			})
+		})
+	})
+
+	Given(t, "a filter that is not a valid regexp", func(when When) {
+
+		defer func(f func() (*regexp.Regexp, error)) { specFilter = f }(specFilter)
+		specFilter = func() (*regexp.Regexp, error) {
+			_, err := regexp.Compile("Given a dog (")
+			return nil, err
+		}
+
+		// the Given fails its test with t.Fatal, which ends the goroutine
+		failing := &testing.T{}
+		ran := false
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			Given(failing, "a dog", func(when When) {
+				ran = true
+			})
+		}()
+		<-done
+
+		when("a Given runs", func(it It) {
+
+			it("should fail its test", func(assert Assert) {
+				assert.True(failing.Failed())
+			})
+
+			it("should not run the Given", func(assert Assert) {
+				assert.False(ran)
+			})
+		})
+	})
+
+	Given(t, "a spec", func(when When) {
+
+		spec := &Specification{
+			Feature: "Washing Dogs",
+			Given:   "a dog\nthat is dirty",
+			When:    "washed",
+			Spec:    "should be clean",
+		}
+
+		when("building its sentence", func(it It) {
+
+			it("should join the Feature, Given, When and It on a single line", func(assert Assert) {
+				assert.Equal("Washing Dogs Given a dog that is dirty When washed It should be clean", spec.sentence())
+			})
+		})
+	})
+}
+
+func Test_Strict_Mode(t *testing.T) {
+
+	SetSilent()
+
+	Given(t, "specs that are NOT IMPLEMENTED", func(when When) {
+
+		stubs := func() *testing.T {
+			t := &testing.T{}
+			Given(t, "a stubbed Given", func(when When) {
+				when("a stubbed When", func(it It) {
+					it("should be stubbed")
+				})
+			})
+			return t
+		}
+
+		when("running leniently", func(it It) {
+
+			lenient := stubs()
+
+			it("should pass", func(assert Assert) {
+				assert.False(lenient.Failed())
+			})
+		})
+
+		when("running strictly", func(it It) {
+
+			SetStrict()
+			strict := stubs()
+			SetLenient()
+
+			it("should fail", func(assert Assert) {
+				assert.True(strict.Failed())
+			})
+		})
+
+		when("the MSPEC_STRICT environment variable is set", func(it It) {
+
+			t.Setenv("MSPEC_STRICT", "true")
+
+			it("should run strictly", func(assert Assert) {
+				assert.True(strictMode(MSpecConfig{}))
+			})
+		})
+	})
+}
+
+func Test_Multiple_Assertions(t *testing.T) {
+
+	SetSilent()
+
+	Given(t, "an It with several assertion funcs", func(when When) {
+
+		when("an earlier assertion fails", func(it It) {
+
+			var ran []int
+			failing := &testing.T{}
+			Given(failing, "a spec", func(when When) {
+				when("it asserts", func(it It) {
+					it("should run every assertion",
+						func(assert Assert) { ran = append(ran, 1) },
+						func(assert Assert) { ran = append(ran, 2); assert.True(false) },
+						func(assert Assert) { ran = append(ran, 3) },
+					)
+				})
+			})
+
+			it("should still run the later assertions in order", func(assert Assert) {
+				assert.Equal([]int{1, 2, 3}, ran)
+			})
+
+			it("should fail the spec", func(assert Assert) {
+				assert.True(failing.Failed())
+			})
+		})
+
+		when("describing the failing assertion", func(it It) {
+
+			named := &Specification{AssertFn: hasNoFailures, assertFns: make([]func(Assert), 3), assertion: 1}
+			literal := &Specification{AssertFn: func(Assert) {}, assertFns: make([]func(Assert), 2)}
+
+			it("should name a declared func", func(assert Assert) {
+				assert.Equal("assertion 2 of 3, hasNoFailures", named.assertionDesc())
+			})
+
+			it("should number a func literal", func(assert Assert) {
+				assert.Equal("assertion 1 of 2", literal.assertionDesc())
+			})
+		})
+	})
+}
+
+func hasNoFailures(assert Assert) {}
+
+func Test_Panics(t *testing.T) {
+
+	SetSilent()
+
+	Given(t, "specs that panic", func(when When) {
+
+		var ran []string
+		panicking := &testing.T{}
+		Given(panicking, "a panicking Given", func(when When) {
+			when("an It panics", func(it It) {
+				it("should panic",
+					func(assert Assert) { panic("boom") },
+					func(assert Assert) { ran = append(ran, "later assertion") },
+				)
+				it("should run the next spec", func(assert Assert) {
+					ran = append(ran, "next spec")
+				})
+			})
+			when("a When panics", func(it It) {
+				panic("boom")
+			})
+			when("the next When runs", func(it It) {
+				ran = append(ran, "next When")
+			})
+		})
+
+		when("running them", func(it It) {
+
+			it("should fail", func(assert Assert) {
+				assert.True(panicking.Failed())
+			})
+
+			it("should run everything after the panics", func(assert Assert) {
+				assert.Equal([]string{"later assertion", "next spec", "next When"}, ran)
+			})
+		})
+
+		when("a When panics only when it runs again in isolated mode", func(it It) {
+
+			runs := 0
+			panicking := &testing.T{}
+			SetIsolated()
+			Given(panicking, "an isolated Given", func(when When) {
+				when("a key is put", func(it It) {
+					runs++
+					if runs > 1 {
+						panic("boom")
+					}
+					it("should be checked", func(assert Assert) {})
+				})
+			})
+			SetShared()
+
+			it("should fail the spec of the pass", func(assert Assert) {
+				assert.True(panicking.Failed())
+			})
+		})
+
+		when("a When no longer declares its spec in isolated mode", func(it It) {
+
+			runs := 0
+			missing := &testing.T{}
+			SetIsolated()
+			Given(missing, "an isolated Given", func(when When) {
+				when("a key is put", func(it It) {
+					runs++
+					if runs == 1 {
+						it("should be checked", func(assert Assert) {})
+					}
+				})
+			})
+			SetShared()
+
+			it("should fail the spec of the pass", func(assert Assert) {
+				assert.True(missing.Failed())
+			})
+		})
+
+		when("trimming the stack of a panic", func(it It) {
+
+			var stack []string
+			func() {
+				defer func() {
+					recover()
+					stack = panicStack()
+				}()
+				panic("boom")
+			}()
+
+			it("should start at the panicking func", func(assert Assert) {
+				assert.Contains(stack[0], "mspec_test.go")
+			})
+
+			it("should leave out the runtime, testing and mspec frames", func(assert Assert) {
+				for _, frame := range stack {
+					assert.NotContains(frame, "runtime.")
+					assert.NotContains(frame, "testing.")
+					assert.Contains(frame, "_test.go")
+				}
+			})
+		})
+	})
+}
+
+func Test_Timeouts(t *testing.T) {
+
+	SetSilent()
+
+	Given(t, "specs with a Timeout", func(when When) {
+
+		when("a spec hangs", func(it It) {
+
+			release := make(chan struct{})
+			defer close(release)
+
+			var ranAfter bool
+			hanging := &testing.T{}
+			Given(hanging, "a hanging Given", func(when When) {
+				when.Timeout(10 * time.Millisecond)
+				when("a spec never returns", func(it It) {
+					it("should hang", func(assert Assert) {
+						<-release
+					})
+					it("should run the next spec", func(assert Assert) {
+						ranAfter = true
+					})
+				})
+			})
+
+			it("should fail", func(assert Assert) {
+				assert.True(hanging.Failed())
+			})
+
+			it("should run the remaining specs", func(assert Assert) {
+				assert.True(ranAfter)
+			})
+		})
+
+		when("a spec goes on after it timed out", func(it It) {
+
+			failed, skipped, late := make(chan struct{}), make(chan struct{}), make(chan struct{})
+			abandoned := &testing.T{}
+			Given(abandoned, "a Given that moves on", func(when When) {
+				when.Timeout(10 * time.Millisecond)
+				when("its specs outlive their Timeout", func(it It) {
+					it("should be abandoned",
+						func(assert Assert) {
+							defer close(failed)
+							time.Sleep(50 * time.Millisecond)
+							assert.True(false)
+						},
+						func(assert Assert) {
+							close(late)
+						},
+					)
+					it("should be abandoned too", func(assert Assert) {
+						defer close(skipped)
+						time.Sleep(50 * time.Millisecond)
+						SpecOf(assert).Skip("too late")
+					})
+				})
+			})
+			<-failed
+			<-skipped
+
+			it("should fail", func(assert Assert) {
+				assert.True(abandoned.Failed())
+				assert.False(abandoned.Skipped())
+			})
+
+			it("should not run the assertions after it", func(assert Assert) {
+				select {
+				case <-late:
+					assert.True(false, "an assertion ran after the timeout")
+				case <-time.After(50 * time.Millisecond):
+				}
+			})
+		})
+
+		when("a spec uses its context", func(it It) {
+
+			var ctx context.Context
+			Given(&testing.T{}, "a Given with a context", func(when When) {
+				when("the spec runs", func(it It) {
+					it.Timeout(time.Minute)
+					it("should have a context", func(assert Assert) {
+						ctx = SpecOf(assert).Context()
+					})
+				})
+			})
+			deadline, ok := ctx.Deadline()
+
+			it("should have the deadline of the Timeout", func(assert Assert) {
+				assert.True(ok)
+				assert.WithinDuration(time.Now().Add(time.Minute), deadline, time.Second)
+			})
+
+			it("should be cancelled once the spec completes", func(assert Assert) {
+				assert.Equal(context.Canceled, ctx.Err())
+			})
+		})
+
+		when("dumping the goroutines of a spec", func(it It) {
+
+			ids := make(chan int)
+			release := make(chan struct{})
+			go func() {
+				ids <- goroutineID()
+				<-release
+			}()
+			stacks := goroutines(<-ids)
+			close(release)
+
+			it("should include the goroutine of the spec", func(assert Assert) {
+				assert.Contains(stacks, "Test_Timeouts")
+			})
+
+			it("should leave out the other goroutines", func(assert Assert) {
+				assert.NotContains(stacks, "testing.tRunner")
+			})
+		})
+	})
+}
+
+func Test_Nested_Steps(t *testing.T) {
+
+	SetSilent()
+
+	Given(t, "a When with nested steps", func(when When) {
+
+		when("running the steps", func(it It) {
+
+			var trace []string
+			Given(&testing.T{}, "a dog", func(when When) {
+				when("the dog is washed", func(it It) {
+					state := "clean"
+					it("should be clean", func(assert Assert) { trace = append(trace, state) })
+
+					it.And("it rolls in mud", func(it It) {
+						state += " and muddy"
+						it("should be muddy", func(assert Assert) { trace = append(trace, state) })
+
+						it.But("it is rinsed", func(it It) {
+							state += " but rinsed"
+							it("should be rinsed", func(assert Assert) { trace = append(trace, state) })
+						})
+					})
+
+					it.When("it is dried", func(it It) {
+						it("should be dry", func(assert Assert) { trace = append(trace, "dry") })
+					})
+				})
+			})
+
+			it("should run each step on the state of its parent", func(assert Assert) {
+				assert.Equal([]string{
+					"clean",
+					"clean and muddy",
+					"clean and muddy but rinsed",
+					"dry",
+				}, trace)
+			})
+		})
+
+		when("rendering the steps", func(it It) {
+
+			spec := Specification{}
+			washed := spec.step("When", "the dog is washed")
+			muddy := washed.step("And", "it rolls in mud")
+			dried := washed.step("When", "it is dried")
+
+			it("should keep an And at the indentation of its parent", func(assert Assert) {
+				assert.Equal("", muddy.indent())
+				assert.Equal("And", muddy.whenKeyword())
+			})
+
+			it("should indent a nested When", func(assert Assert) {
+				assert.Equal("  ", dried.indent())
+			})
+
+			it("should read the steps as a sentence", func(assert Assert) {
+				assert.Equal("When the dog is washed And it rolls in mud", muddy.whens())
+			})
+		})
+	})
+}
+
+func Test_Outline(t *testing.T) {
+
+	SetSilent()
+
+	Given(t, "a Scenario Outline", func(when When) {
+
+		when("running its Examples", func(it It) {
+
+			var counts []interface{}
+			failing := &testing.T{}
+			Outline(failing, "a cart with <count> items", Examples{
+				{"count": 1, "total": 10},
+				{"count": 3, "total": 31},
+			}, func(when When, row Row) {
+				counts = append(counts, row["count"])
+
+				when("totalled", func(it It) {
+					it("should cost <total>", func(assert Assert) {
+						assert.Equal(row["total"], row["count"].(int)*10)
+					})
+				})
+			})
+
+			it("should run the Given once per row", func(assert Assert) {
+				assert.Equal([]interface{}{1, 3}, counts)
+			})
+
+			it("should fail when a row fails", func(assert Assert) {
+				assert.True(failing.Failed())
+			})
+		})
+
+		when("substituting the placeholders", func(it It) {
+
+			row := Row{"count": 3, "item": "apples"}
+
+			it("should replace each placeholder with its value", func(assert Assert) {
+				assert.Equal("should cost 3 apples", row.substitute("should cost <count> <item>"))
+			})
+
+			it("should leave unknown placeholders", func(assert Assert) {
+				assert.Equal("should cost <total>", row.substitute("should cost <total>"))
+			})
+
+			it("should render the row", func(assert Assert) {
+				assert.Equal("<count> 3, <item> apples", row.String())
+			})
+		})
+	})
+}
+
+func Test_Random_Order(t *testing.T) {
+
+	SetSilent()
+
+	Given(t, "specs run in a random order", func(when When) {
+
+		run := func() []string {
+			var order []string
+			Given(&testing.T{}, "a Given with many specs", func(when When) {
+				for _, w := range []string{"a", "b", "c", "d"} {
+					w := w
+					when(w, func(it It) {
+						for _, i := range []string{"1", "2", "3", "4"} {
+							i := i
+							it(i, func(assert Assert) { order = append(order, w+i) })
+						}
+					})
+				}
+			})
+			return order
+		}
+
+		when("replaying a seed", func(it It) {
+
+			t.Setenv("MSPEC_SEED", "42")
+			first, second := run(), run()
+
+			it("should run every spec once", func(assert Assert) {
+				assert.Len(first, 16)
+			})
+
+			it("should run the specs in the same order", func(assert Assert) {
+				assert.Equal(first, second)
+			})
+		})
+
+		when("using different seeds", func(it It) {
+
+			t.Setenv("MSPEC_SEED", "1")
+			first := run()
+			t.Setenv("MSPEC_SEED", "2")
+			second := run()
+
+			it("should run the specs in different orders", func(assert Assert) {
+				assert.NotEqual(first, second)
+			})
+		})
+
+		when("picking a seed", func(it It) {
+
+			t.Setenv("MSPEC_SEED", "")
+			SetRandomOrder()
+			seed, random := randomSeed(currentConfig())
+			again, _ := randomSeed(currentConfig())
+			SetDeclaredOrder()
+
+			it("should shuffle the specs", func(assert Assert) {
+				assert.True(random)
+			})
+
+			it("should keep the seed for the whole test binary", func(assert Assert) {
+				assert.Equal(seed, again)
+			})
+		})
+
+		when("running in the declared order", func(it It) {
+
+			t.Setenv("MSPEC_SEED", "")
+			order := run()
+
+			it("should not shuffle the specs", func(assert Assert) {
+				assert.Equal([]string{"a1", "a2", "a3", "a4"}, order[:4])
+			})
+		})
+	})
+}
+
+func Test_Eventually_And_Consistently(t *testing.T) {
+
+	SetSilent()
+
+	Given(t, "polling assertions", func(when When) {
+
+		spec := &Specification{run: newRunContext()}
+
+		when("a condition is met after a few attempts", func(it It) {
+
+			attempts := 0
+			passed := spec.Eventually(func(assert Assert) {
+				attempts++
+				assert.Equal(3, attempts)
+			}, time.Second, time.Millisecond)
+
+			it("should pass", func(assert Assert) {
+				assert.True(passed)
+				assert.False(spec.AssertionFailed)
+			})
+
+			it("should stop polling once it passes", func(assert Assert) {
+				assert.Equal(3, attempts)
+			})
+		})
+
+		when("a condition is never met", func(it It) {
+
+			spec := &Specification{run: newRunContext()}
+			passed := spec.Eventually(func(assert Assert) {
+				assert.True(false)
+			}, 5*time.Millisecond, time.Millisecond)
+
+			it("should fail", func(assert Assert) {
+				assert.False(passed)
+				assert.True(spec.AssertionFailed)
+			})
+		})
+
+		when("the last attempt is recorded", func(it It) {
+
+			failure := spec.try(func(assert Assert) {
+				assert.Equal(1, 2)
+			})
+
+			it("should report its failure", func(assert Assert) {
+				assert.Contains(failure, "Not equal")
+			})
+		})
+
+		when("a condition holds throughout", func(it It) {
+
+			attempts := 0
+			passed := spec.Consistently(func(assert Assert) {
+				attempts++
+				assert.True(true)
+			}, 5*time.Millisecond, time.Millisecond)
+
+			it("should pass", func(assert Assert) {
+				assert.True(passed)
+			})
+
+			it("should poll for the whole duration", func(assert Assert) {
+				assert.True(attempts > 1)
+			})
+		})
+
+		when("a condition stops holding", func(it It) {
+
+			spec := &Specification{run: newRunContext()}
+			attempts := 0
+			passed := spec.Consistently(func(assert Assert) {
+				attempts++
+				assert.True(attempts < 2)
+			}, time.Second, time.Millisecond)
+
+			it("should fail at once", func(assert Assert) {
+				assert.False(passed)
+				assert.Equal(2, attempts)
+			})
+		})
+	})
+}
+
+func Test_Labels(t *testing.T) {
+
+	SetSilent()
+
+	Given(t, "specs with labels", func(when When) {
+
+		when("splitting the labels off a title", func(it It) {
+
+			title, labels := splitLabels("should sync the accounts @slow @db")
+			plain, none := splitLabels("should email @support")
+
+			it("should strip the trailing labels", func(assert Assert) {
+				assert.Equal("should sync the accounts", title)
+				assert.Equal([]string{"slow", "db"}, labels)
+			})
+
+			it("should leave a title without trailing labels", func(assert Assert) {
+				assert.Equal("should email", plain)
+				assert.Equal([]string{"support"}, none)
+				assert.Equal("should email support@example.com", subtestName("should email support@example.com"))
+			})
+		})
+
+		when("filtering by labels", func(it It) {
+
+			var ran []string
+			record := func(spec string) func(Assert) {
+				return func(Assert) { ran = append(ran, spec) }
+			}
+
+			defer func(f func() (labelExpr, error)) { labelFilter = f }(labelFilter)
+			labelFilter = func() (labelExpr, error) {
+				return parseLabels("db && !slow")
+			}
+
+			Given(&testing.T{}, "a database @db", func(when When) {
+				when("syncing @slow", func(it It) {
+					it("should sync", record("sync"))
+				})
+				when("reading", func(it It) {
+					it("should read", record("read"))
+					it("should cache @slow", record("cache"))
+				})
+			})
+			Given(&testing.T{}, "a queue", func(when When) {
+				when("reading", func(it It) {
+					it("should read", record("queue"))
+				})
+			})
+
+			it("should inherit the labels of the Given and Whens", func(assert Assert) {
+				assert.Equal([]string{"read"}, ran)
+			})
+		})
+
+		when("the expression does not parse", func(it It) {
+
+			defer func(f func() (labelExpr, error)) { labelFilter = f }(labelFilter)
+			labelFilter = func() (labelExpr, error) {
+				return parseLabels("db &&")
+			}
+
+			// the Given fails its test with t.Fatal, which ends the goroutine
+			failing := &testing.T{}
+			ran := false
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				Given(failing, "a database @db", func(when When) {
+					ran = true
+				})
+			}()
+			<-done
+
+			it("should fail the test of the Given, without running it", func(assert Assert) {
+				assert.True(failing.Failed())
+				assert.False(ran)
+			})
+		})
+
+		when("parsing label expressions", func(it It) {
+
+			match := func(expr string, labels ...string) bool {
+				e, err := parseLabels(expr)
+				if err != nil {
+					panic(err)
+				}
+				return e.match(labels)
+			}
+
+			it("should match a single label", func(assert Assert) {
+				assert.True(match("db", "db", "slow"))
+				assert.False(match("@db", "slow"))
+			})
+
+			it("should negate, combine and group labels", func(assert Assert) {
+				assert.True(match("!slow && db", "db"))
+				assert.False(match("!slow && db", "db", "slow"))
+				assert.True(match("slow || db", "slow"))
+				assert.True(match("db && !(slow || flaky)", "db"))
+				assert.False(match("db && !(slow || flaky)", "db", "flaky"))
+			})
+
+			it("should reject invalid expressions", func(assert Assert) {
+				for _, expr := range []string{"db &&", "(db", "db)", "db & slow", "&& db"} {
+					_, err := parseLabels(expr)
+					assert.Error(err, expr)
+				}
+			})
+		})
+	})
+}
+
+var aCounter = DefineBehavior("a counter", func(when When, counter *int) {
+
+	when("it is incremented", func(it It) {
+		*counter++
+
+		it("should count up from where it was", func(assert Assert) {
+			assert.True(*counter > 0)
+		})
+	})
+})
+
+func Test_Shared_Behaviors(t *testing.T) {
+
+	SetSilent()
+
+	Given(t, "a behavior shared by Givens", func(when When) {
+
+		when("it is included for several subjects", func(it It) {
+
+			from0, fromMinus2 := 0, -2
+			st := &testing.T{}
+			Given(st, "a counter from 0", aCounter.For(&from0))
+			Given(st, "a counter from -2", func(when When) {}, aCounter.For(&fromMinus2))
+
+			it("should run its specs for each subject", func(assert Assert) {
+				assert.Equal(1, from0)
+				assert.Equal(-1, fromMinus2)
+			})
+
+			it("should fail the Givens of the subjects that do not behave", func(assert Assert) {
+				assert.True(st.Failed())
+			})
+		})
+
+		when("its specs are declared", func(it It) {
+
+			var included, after string
+			within := DefineBehavior("a probe", func(when When, _ struct{}) {
+				included = when.scope().spec.behavior
+			})
+			Given(&testing.T{}, "a Given", within.For(struct{}{}), func(when When) {
+				after = when.scope().spec.behavior
+			})
+
+			it("should know where the behavior was included", func(assert Assert) {
+				assert.True(regexp.MustCompile(`^behaves like a probe, included at mspec_test\.go:\d+$`).MatchString(included), included)
+			})
+
+			it("should leave the specs declared after it alone", func(assert Assert) {
+				assert.Empty(after)
+			})
+		})
+	})
+}
+
+type counter interface {
+	Increment() int
+}
+
+type countingUp struct{ n int }
+
+func (c *countingUp) Increment() int { c.n++; return c.n }
+
+type countingNowhere struct{}
+
+func (countingNowhere) Increment() int { return 0 }
+
+var aCounterSuite = DefineSuite[counter]("a counter", func(when When, newCounter func() counter) {
+
+	when("it is incremented", func(it It) {
+		c := newCounter()
+
+		it("should count up", func(assert Assert) {
+			assert.Equal(1, c.Increment())
+		})
+	})
+})
+
+func Test_Interface_Suites(t *testing.T) {
+
+	SetSilent()
+
+	Given(t, "a suite written against an interface", func(when When) {
+
+		when("it runs against an implementation that conforms", func(it It) {
+
+			constructed := 0
+			st := &testing.T{}
+			aCounterSuite.Run(st, "counting up", func() counter {
+				constructed++
+				return &countingUp{}
+			})
+
+			it("should pass", func(assert Assert) {
+				assert.False(st.Failed())
+			})
+
+			it("should only construct the implementations its specs ask for", func(assert Assert) {
+				assert.Equal(1, constructed)
+			})
+		})
+
+		when("it runs against an implementation that does not", func(it It) {
+
+			st := &testing.T{}
+			aCounterSuite.Run(st, "counting nowhere", func() counter { return countingNowhere{} })
+
+			it("should fail", func(assert Assert) {
+				assert.True(st.Failed())
+			})
+		})
+
+		when("the constructor returns nil", func(it It) {
+
+			st := &testing.T{}
+			aCounterSuite.Run(st, "nil", func() counter { return nil })
+
+			it("should fail", func(assert Assert) {
+				assert.True(st.Failed())
+			})
+		})
+
+		when("it is defined for a type that is not an interface", func(it It) {
+
+			it("should panic", func(assert Assert) {
+				defer func() {
+					assert.NotNil(recover())
+				}()
+				DefineSuite[int]("an int")
+			})
+		})
+	})
+}
+
+type perSpecFixture struct{ id int }
+type perGivenFixture struct{ id int }
+type undefinedFixture struct{}
+type storeFixture struct{ m map[string]int }
+
+func Test_Fixtures(t *testing.T) {
+
+	SetSilent()
+
+	Given(t, "fixtures defined by their type", func(when When) {
+
+		var built, tornDown []string
+		define := func() {
+			specs, givens := 0, 0
+			DefineFixture(PerSpec, func() *perSpecFixture {
+				specs++
+				built = append(built, fmt.Sprintf("spec %d", specs))
+				return &perSpecFixture{specs}
+			}, func(f *perSpecFixture) {
+				tornDown = append(tornDown, fmt.Sprintf("spec %d", f.id))
+			})
+			DefineFixture(PerGiven, func() *perGivenFixture {
+				givens++
+				built = append(built, fmt.Sprintf("given %d", givens))
+				return &perGivenFixture{givens}
+			}, func(f *perGivenFixture) {
+				tornDown = append(tornDown, fmt.Sprintf("given %d", f.id))
+			})
+		}
+
+		when("the specs of a Given request them", func(it It) {
+
+			built, tornDown = nil, nil
+			define()
+			var ids []int
+			Given(&testing.T{}, "a Given", func(when When) {
+				when("a When", func(it It) {
+					record := func(assert Assert) {
+						spec := Let[*perSpecFixture](assert)
+						given := Let[*perGivenFixture](assert)
+						ids = append(ids, spec.id, Let[*perSpecFixture](assert).id, given.id)
+					}
+					it("should get its own", record)
+					it("should get another of its own", record)
+				})
+			})
+
+			it("should build them lazily, once per lifetime", func(assert Assert) {
+				assert.Equal([]string{"spec 1", "given 1", "spec 2"}, built)
+				assert.Equal([]int{1, 1, 1, 2, 2, 1}, ids)
+			})
+
+			it("should tear them down as their lifetimes end", func(assert Assert) {
+				assert.Equal([]string{"spec 1", "spec 2", "given 1"}, tornDown)
+			})
+		})
+
+		when("a When requests them in isolated mode", func(it It) {
+
+			built, tornDown = nil, nil
+			define()
+			var ids []int
+			SetIsolated()
+			Given(&testing.T{}, "a Given", func(when When) {
+				when("a When", func(it It) {
+					spec := Let[*perSpecFixture](it)
+					given := Let[*perGivenFixture](when)
+					record := func(assert Assert) {
+						ids = append(ids, spec.id, given.id)
+					}
+					it("should get its own", record)
+					it("should get another of its own", record)
+				})
+			})
+			SetShared()
+
+			it("should build them for every run of the When", func(assert Assert) {
+				assert.Equal([]int{2, 1, 3, 1}, ids)
+			})
+
+			it("should tear them down after every run of the When", func(assert Assert) {
+				assert.Equal([]string{"spec 1", "spec 2", "spec 3", "given 1"}, tornDown)
+			})
+		})
+
+		when("a When uses them in isolated mode", func(it It) {
+
+			DefineFixture(PerSpec, func() *storeFixture {
+				return &storeFixture{m: map[string]int{}}
+			}, nil)
+			var values []int
+			st := &testing.T{}
+			SetIsolated()
+			Given(st, "a Given", func(when When) {
+				when("a key is put", func(it It) {
+					s := Let[*storeFixture](it)
+					s.m["k"]++
+					record := func(assert Assert) {
+						values = append(values, s.m["k"])
+					}
+					it("should get the key", record)
+					it("should get the key again", record)
+				})
+			})
+			SetShared()
+
+			it("should run every spec with a fixture of its own", func(assert Assert) {
+				assert.False(st.Failed())
+				assert.Equal([]int{1, 1}, values)
+			})
+		})
+
+		when("a When and its specs request them", func(it It) {
+
+			built, tornDown = nil, nil
+			define()
+			var same, bySpec []bool
+			for _, isolated := range []bool{false, true} {
+				if isolated {
+					SetIsolated()
+				}
+				Given(&testing.T{}, "a Given", func(when When) {
+					when("a When", func(it It) {
+						fixture := Let[*perSpecFixture](it)
+						record := func(assert Assert) {
+							same = append(same, Let[*perSpecFixture](assert) == fixture)
+							bySpec = append(bySpec, Let[*perSpecFixture](SpecOf(assert)) == fixture)
+						}
+						it("should get the fixture of the When", record)
+						it("should get it again", record)
+					})
+				})
+				SetShared()
+			}
+
+			it("should get the same fixture in the When and its specs", func(assert Assert) {
+				assert.Equal([]bool{true, true, true, true}, same)
+				assert.Equal([]bool{true, true, true, true}, bySpec)
+			})
+		})
+
+		when("no fixture of the type is defined", func(it It) {
+
+			st := &testing.T{}
+			Given(st, "a Given", func(when When) {
+				when("a When", func(it It) {
+					it("should fail", func(assert Assert) {
+						Let[undefinedFixture](assert)
+					})
+				})
+			})
+
+			it("should fail the spec", func(assert Assert) {
+				assert.True(st.Failed())
+			})
+		})
+	})
+}
+
+type report struct {
+	Title string
+	Pages int
+}
+
+// reportSnapshots matches a report against its snapshots, which are named
+// after this func as it calls Given.
+func reportSnapshots(title string) *testing.T {
+	st := &testing.T{}
+	Given(st, "a report", func(when When) {
+		when("it is rendered", func(it It) {
+			it("should match its snapshots", func(assert Assert) {
+				SpecOf(assert).MatchSnapshot("title: " + title + "\n")
+				SpecOf(assert).MatchSnapshot(report{title, 3})
+			})
+		})
+	})
+	return st
+}
+
+func Test_Snapshots(t *testing.T) {
+
+	SetSilent()
+	t.Chdir(t.TempDir())
+
+	Given(t, "values matched against their snapshots", func(when When) {
+
+		when("there are no snapshots yet", func(it It) {
+
+			st := reportSnapshots("Q1")
+
+			it("should fail", func(assert Assert) {
+				assert.True(st.Failed())
+			})
+		})
+
+		when("the snapshots are updated", func(it It) {
+
+			t.Setenv("MSPEC_UPDATE", "1")
+			st := reportSnapshots("Q1")
+			t.Setenv("MSPEC_UPDATE", "")
+
+			it("should pass", func(assert Assert) {
+				assert.False(st.Failed())
+			})
+
+			it("should write a golden file for each", func(assert Assert) {
+				dir := filepath.Join("testdata", "reportSnapshots", "a_report", "it_is_rendered")
+				title, err := os.ReadFile(filepath.Join(dir, "should_match_its_snapshots.golden"))
+				assert.NoError(err)
+				assert.Equal("title: Q1\n", string(title))
+
+				pretty, err := os.ReadFile(filepath.Join(dir, "should_match_its_snapshots_2.golden"))
+				assert.NoError(err)
+				assert.Equal("{\n  \"Title\": \"Q1\",\n  \"Pages\": 3\n}\n", string(pretty))
+			})
+		})
+
+		when("the values match their snapshots", func(it It) {
+
+			st := reportSnapshots("Q1")
+
+			it("should pass", func(assert Assert) {
+				assert.False(st.Failed())
+			})
+		})
+
+		when("the values differ from their snapshots", func(it It) {
+
+			st := reportSnapshots("Q2")
+
+			it("should fail", func(assert Assert) {
+				assert.True(st.Failed())
+			})
+		})
+
+		when("the lines of a snapshot differ", func(it It) {
+
+			diff := diffLines("a\nb\nc\nd\ne\nf\n", "a\nb\nc\nD\ne\nf\n")
+
+			it("should show the changes with the lines around them", func(assert Assert) {
+				assert.Equal("  ...\n  b\n  c\n- d\n+ D\n  e\n  f\n  ...", diff)
+			})
+		})
+
+		when("the snapshots are huge", func(it It) {
+
+			var expected, actual, changed []string
+			for i := 0; i < 10000; i++ {
+				expected = append(expected, fmt.Sprintf("line %d", i))
+				actual = append(actual, fmt.Sprintf("other line %d", i))
+				changed = append(changed, fmt.Sprintf("line %d", i))
+			}
+			changed[5000] = "changed"
+			oneChange := diffLines(strings.Join(expected, "\n"), strings.Join(changed, "\n"))
+			allChanged := diffLines(strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+
+			it("should only compare the lines between those that are the same", func(assert Assert) {
+				assert.Equal("  ...\n  line 4998\n  line 4999\n- line 5000\n+ changed\n  line 5001\n  line 5002\n  ...", oneChange)
+			})
+
+			it("should only show the first lines that differ when too many do", func(assert Assert) {
+				assert.Equal("- line 0\n+ other line 0\n  ... and 9999 more lines of the snapshot, 9999 of the actual value, too many to compare", allChanged)
+			})
+		})
+
+		when("MSPEC_UPDATE is set to false", func(it It) {
+
+			t.Setenv("MSPEC_UPDATE", "false")
+			update := updateSnapshots()
+			t.Setenv("MSPEC_UPDATE", "")
+
+			it("should match the snapshots rather than update them", func(assert Assert) {
+				assert.False(update)
+			})
+		})
+	})
+}
+
+const counting = `@counting
+Feature: Counting
+
+  Background:
+    Given a counter
+
+  Scenario: Adding
+    Given the counter is at 2
+    When 3 is added
+    Then the counter should be at 5
+
+  Scenario Outline: Adding many
+    When these are added:
+      | n   |
+      | <a> |
+      | <b> |
+    Then the counter should be at <sum>
+
+    Examples:
+      | a | b | sum |
+      | 1 | 2 | 3   |
+      | 4 | 5 | 10  |
+`
+
+const subtracting = `Feature: Subtracting
+
+  Scenario: Subtracting
+    Given a counter
+    When 1 is subtracted
+    Then the counter should be at -1
+`
+
+const journey = `Feature: A journey
+
+  Scenario: Counting twice
+    Given a counter
+    When 1 is added
+    Then the counter should be at 1
+    When 2 is added
+    Then the counter should be at 3
+`
+
+func Test_Gherkin_Features(t *testing.T) {
+
+	SetSilent()
+
+	dir := t.TempDir()
+	pending := t.TempDir()
+	journeys := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "counting.feature"), []byte(counting), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pending, "subtracting.feature"), []byte(subtracting), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(journeys, "journey.feature"), []byte(journey), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	Given(t, "the scenarios of a feature file", func(when When) {
+
+		var counter int
+		var ran, givens, names []string
+		steps := &Steps{}
+		steps.Before(func() {
+			counter = -100
+		})
+		steps.Step(`a counter`, func() {
+			counter = 0
+		})
+		steps.Step(`the counter is at {int}`, func(n int) {
+			counter = n
+		})
+		steps.Step(`^(\d+) is added$`, func(n int) {
+			counter += n
+		})
+		steps.Step(`these are added:`, func(table gherkin.Table) {
+			for _, row := range table[1:] {
+				n, _ := strconv.Atoi(row[0])
+				counter += n
+			}
+		})
+		steps.Step(`the counter should be at {int}`, func(assert Assert, n int) {
+			ran = append(ran, fmt.Sprint(n))
+			givens = append(givens, SpecOf(assert).Given)
+			names = append(names, SpecOf(assert).T.Name())
+			assert.Equal(n, counter)
+		})
+
+		when("they run against the step definitions", func(it It) {
+
+			st := &testing.T{}
+			Features(st, filepath.Join(dir, "*.feature"), steps)
+
+			it("should run every scenario, and every row of the outlines", func(assert Assert) {
+				assert.Equal([]string{"5", "3", "10"}, ran)
+			})
+
+			it("should fail the scenarios that fail", func(assert Assert) {
+				assert.True(st.Failed())
+			})
+		})
+
+		when("they are filtered by their tags", func(it It) {
+
+			ran = nil
+			labels := labelFilter
+			labelFilter = func() (labelExpr, error) {
+				return parseLabels("!counting")
+			}
+			Features(&testing.T{}, filepath.Join(dir, "*.feature"), steps)
+			labelFilter = labels
+
+			it("should skip the scenarios without the labels", func(assert Assert) {
+				assert.Empty(ran)
+			})
+		})
+
+		when("a step matches no definition", func(it It) {
+
+			ran = nil
+			lenient := &testing.T{}
+			Features(lenient, filepath.Join(pending, "*.feature"), steps)
+			SetStrict()
+			strict := &testing.T{}
+			Features(strict, filepath.Join(pending, "*.feature"), steps)
+			SetLenient()
+
+			it("should not implement the specs after it", func(assert Assert) {
+				assert.Empty(ran)
+				assert.False(lenient.Failed())
+			})
+
+			it("should fail in strict mode", func(assert Assert) {
+				assert.True(strict.Failed())
+			})
+		})
+
+		when("a scenario goes on after its Then steps", func(it It) {
+
+			ran, givens = nil, nil
+			st := &testing.T{}
+			Features(st, filepath.Join(journeys, "*.feature"), steps)
+
+			it("should check each Then after the step right before it", func(assert Assert) {
+				assert.Equal([]string{"1", "3"}, ran)
+				assert.False(st.Failed())
+			})
+
+			it("should name the Given after the scenario", func(assert Assert) {
+				assert.NotEmpty(givens)
+				for _, given := range givens {
+					assert.True(strings.HasPrefix(given, "Counting twice"))
+					assert.Contains(given, "Given a counter")
+				}
+			})
+
+			it("should name the subtest of the Given after the scenario alone", func(assert Assert) {
+				names = nil
+				SpecOf(assert).T.Run("features", func(t *testing.T) {
+					Features(t, filepath.Join(journeys, "*.feature"), steps)
+				})
+				assert.NotEmpty(names)
+				for _, name := range names {
+					assert.Contains(name, "/features/A_journey/Counting_twice/1_is_added/")
+				}
+			})
+		})
+
+		when("a step is defined with a func that does not match it", func(it It) {
+
+			it("should panic", func(assert Assert) {
+				defer func() {
+					assert.NotNil(recover())
+				}()
+				steps.Step(`{int} and {int}`, func(n int) {})
+			})
+		})
+	})
+}
+
+func Test_Cleanups(t *testing.T) {
+
+	SetSilent()
+
+	Given(t, "specs that clean up after themselves", func(when When) {
+
+		var ran []string
+		record := func(step string) func() {
+			return func() { ran = append(ran, step) }
+		}
+		declare := func(when When) {
+			when.Cleanup(record("given cleanup"))
+			when("a When", func(it It) {
+				it.Cleanup(record("when cleanup 1"))
+				it.Cleanup(record("when cleanup 2"))
+				it.AfterEach(record("after each"))
+				it("should fail", func(assert Assert) {
+					SpecOf(assert).Cleanup(record("spec cleanup 1"))
+					SpecOf(assert).Cleanup(record("spec cleanup 2"))
+					assert.True(false)
+				})
+			})
+		}
+		expected := []string{
+			"after each",
+			"spec cleanup 2", "spec cleanup 1",
+			"when cleanup 2", "when cleanup 1",
+			"given cleanup",
+		}
+
+		when("a spec fails", func(it It) {
+
+			ran = nil
+			Given(&testing.T{}, "a Given", declare)
+
+			it("should run every cleanup, last registered first", func(assert Assert) {
+				assert.Equal(expected, ran)
+			})
+		})
+
+		when("a spec fails in isolated mode", func(it It) {
+
+			ran = nil
+			SetIsolated()
+			Given(&testing.T{}, "a Given", declare)
+			SetShared()
+
+			it("should run the cleanups of discovering the specs too", func(assert Assert) {
+				assert.Equal(append([]string{"when cleanup 2", "when cleanup 1", "given cleanup"}, expected...), ran)
+			})
+		})
+
+		when("a spec runs as a subtest", func(it It) {
+
+			ran = nil
+			t.Run("hosting", func(t *testing.T) {
+				Given(t, "a Given", func(when When) {
+					when("a When", func(it It) {
+						it.Cleanup(record("when cleanup"))
+						it("should pass", func(assert Assert) {
+							SpecOf(assert).Cleanup(record("spec cleanup"))
+							t.Cleanup(record("test cleanup"))
+						})
+					})
+				})
+			})
+
+			it("should clean up with the subtest of the spec", func(assert Assert) {
+				assert.Equal([]string{"spec cleanup", "when cleanup", "test cleanup"}, ran)
+			})
+		})
+
+		when("a cleanup panics", func(it It) {
+
+			ran = nil
+			st := &testing.T{}
+			Given(st, "a Given", func(when When) {
+				when("a When", func(it It) {
+					it("should fail", func(assert Assert) {
+						SpecOf(assert).Cleanup(record("cleaned up"))
+						SpecOf(assert).Cleanup(func() { panic("boom") })
+					})
+				})
+			})
+
+			it("should fail the spec", func(assert Assert) {
+				assert.True(st.Failed())
+			})
+
+			it("should still run the other cleanups", func(assert Assert) {
+				assert.Equal([]string{"cleaned up"}, ran)
+			})
+		})
+	})
+}
+
+func Test_Skipping_At_Runtime(t *testing.T) {
+
+	SetSilent()
+
+	Given(t, "specs that skip themselves", func(when When) {
+
+		when("an assertion skips its spec", func(it It) {
+
+			var ran []string
+			st := &testing.T{}
+			Given(st, "a Given", func(when When) {
+				when("a spec skips", func(it It) {
+					it("should be skipped",
+						func(assert Assert) {
+							ran = append(ran, "before")
+							SpecOf(assert).Skip("not on this platform")
+							ran = append(ran, "after")
+						},
+						func(assert Assert) { ran = append(ran, "next assertion") })
+					it("should still run the next spec", func(assert Assert) {
+						ran = append(ran, "next spec")
+					})
+				})
+			})
+
+			it("should stop the spec, and only the spec", func(assert Assert) {
+				assert.Equal([]string{"before", "next spec"}, ran)
+			})
+
+			it("should not fail", func(assert Assert) {
+				assert.False(st.Failed())
+			})
+		})
+
+		when("it skips while polling", func(it It) {
+
+			attempts := 0
+			Given(&testing.T{}, "a Given", func(when When) {
+				when("a spec skips in Eventually", func(it It) {
+					it("should be skipped", func(assert Assert) {
+						SpecOf(assert).Eventually(func(assert Assert) {
+							attempts++
+							SpecOf(assert).Skip("no server to poll")
+						}, time.Second, time.Millisecond)
+					})
+				})
+			})
+
+			it("should skip the spec rather than retry", func(assert Assert) {
+				assert.Equal(1, attempts)
+			})
+		})
+
+		when("it skips after a failure", func(it It) {
+
+			st := &testing.T{}
+			Given(st, "a Given", func(when When) {
+				when("a spec fails and then skips", func(it It) {
+					it("should fail",
+						func(assert Assert) { assert.True(false) },
+						func(assert Assert) { SpecOf(assert).Skip("too late") })
+				})
+			})
+
+			it("should still fail", func(assert Assert) {
+				assert.True(st.Failed())
+			})
+		})
+	})
+}
+
+func Test_Fail_Fast(t *testing.T) {
 
 	SetSilent()
 
-	Given(t, "an mspec instance", func(when When) {
+	Given(t, "specs that fail fast", func(when When) {
+
+		var ran []string
+		record := func(spec string) func(Assert) {
+			return func(Assert) { ran = append(ran, spec) }
+		}
+		failing := func(assert Assert) { assert.NoError(fmt.Errorf("boom")) }
+
+		when("a When fails fast", func(it It) {
+
+			ran = nil
+			Given(&testing.T{}, "a Given", func(when When) {
+				when("its foundation fails", func(it It) {
+					it.FailFast()
+					it("should fail", failing)
+					it("should be skipped", record("skipped"))
+				})
+				when("another When runs", func(it It) {
+					it("should still run", record("ran"))
+				})
+			})
+
+			it("should skip the rest of the When", func(assert Assert) {
+				assert.Equal([]string{"ran"}, ran)
+			})
+		})
+
+		when("another When has the same text", func(it It) {
+
+			ran = nil
+			store := DefineBehavior("a store", func(when When, works func(Assert)) {
+				when("it is used", func(it It) {
+					it.FailFast()
+					it("works", works)
+					it("still works", record("still works"))
+				})
+			})
+			Given(&testing.T{}, "two stores", store.For(failing), store.For(record("works")))
+
+			it("should only skip the rest of the When that failed", func(assert Assert) {
+				assert.Equal([]string{"works", "still works"}, ran)
+			})
+		})
+
+		when("a Given fails fast", func(it It) {
+
+			ran = nil
+			Given(&testing.T{}, "a Given", func(when When) {
+				when.FailFast()
+				when("its foundation fails", func(it It) {
+					it("should fail", failing)
+				})
+				when("another When runs", func(it It) {
+					it("should be skipped", record("skipped"))
+				})
+			})
+
+			it("should skip the rest of the Given", func(assert Assert) {
+				assert.Empty(ran)
+			})
+		})
+
+		when("failing fast is configured in isolated mode", func(it It) {
+
+			ran = nil
+			SetFailFast()
+			SetIsolated()
+			Given(&testing.T{}, "a Given", func(when When) {
+				when("its foundation fails", func(it It) {
+					it("should fail", failing)
+					it("should be skipped", record("skipped"))
+				})
+			})
+			Given(&testing.T{}, "the next Given", func(when When) {
+				when("it runs", func(it It) {
+					it("should run", record("ran"))
+				})
+			})
+			SetShared()
+			SetContinueOnFailure()
+
+			it("should skip the rest of the Given", func(assert Assert) {
+				assert.Equal([]string{"ran"}, ran)
+			})
+		})
+
+		when("not failing fast", func(it It) {
+
+			ran = nil
+			Given(&testing.T{}, "a Given", func(when When) {
+				when("a spec fails", func(it It) {
+					it("should fail", failing)
+					it("should run", record("ran"))
+				})
+			})
+
+			it("should run every spec", func(assert Assert) {
+				assert.Equal([]string{"ran"}, ran)
+			})
+		})
+	})
+}
+
+func Test_Measurement_Budgets(t *testing.T) {
+
+	SetSilent()
 
-		f := "feature value"
+	Given(t, "a measured action", func(when When) {
 
-		c := &MSpecConfig{
-			lastFeature: f,
-			lastGiven:   "context value",
-			lastWhen:    "when value",
-			lastSpec:    "title value",
+		m := &Measurement{testing.BenchmarkResult{N: 10, T: 1000, MemAllocs: 20, MemBytes: 640}}
+		check := func(fn func(Assert)) bool {
+			spec := &Specification{run: newRunContext()}
+			fn(newAssertions(spec))
+			return !spec.AssertionFailed
 		}
 
-		when("calling reset()", func(it It) {
+		when("it is within its budgets", func(it It) {
 
-			c.resetLasts()
+			it("should pass", func(assert Assert) {
+				assert.True(check(m.MaxNsPerOp(100)))
+				assert.True(check(m.MaxAllocsPerOp(2)))
+				assert.True(check(m.MaxBytesPerOp(64)))
+			})
+		})
 
-			it("should not reset lastFeature as that is used globally", func(assert Assert) {
-				assert.NotEmpty(c.lastFeature)
+		when("it is over its budgets", func(it It) {
+
+			it("should fail", func(assert Assert) {
+				assert.False(check(m.MaxNsPerOp(99)))
+				assert.False(check(m.MaxAllocsPerOp(1)))
+				assert.False(check(m.MaxBytesPerOp(63)))
 			})
+		})
 
-			it("should keep the lastFeature value", func(assert Assert) {
-				assert.Equal(c.lastFeature, f)
+		when("it was not measured", func(it It) {
+
+			it("should fail", func(assert Assert) {
+				assert.False(check((&Measurement{}).MaxNsPerOp(100)))
 			})
+		})
+
+		when("a budget fails", func(it It) {
+
+			spec := &Specification{run: &runContext{config: MSpecConfig{output: outputStdout}}}
+			_, _, line, _ := runtime.Caller(0)
+			budget := m.MaxAllocsPerOp(1)
+			budget(newAssertions(spec))
 
-			it("should set lastGiven to zero value", func(assert Assert) {
-				assert.Empty(c.lastGiven)
+			it("should be reported where the budget was declared", func(assert Assert) {
+				assert.Contains(spec.run.out.String(), fmt.Sprintf("in mspec_test.go:%d", line+1))
+				assert.Contains(spec.run.out.String(), "2 allocs/op is over the budget of 1 allocs/op")
 			})
+		})
+	})
+}
+
+func Test_Fuzzed_Inputs(t *testing.T) {
 
-			it("should set lastWhen to zero value", func(assert Assert) {
-				assert.Empty(c.lastWhen)
+	Given(t, "an input of a fuzz test", func(when When) {
+
+		when("it is a string or bytes", func(it It) {
+
+			it("should be quoted so it can be read", func(assert Assert) {
+				assert.Equal(`"a\x00b\n"`, fuzzInput("a\x00b\n"))
+				assert.Equal(`"\xff"`, fuzzInput([]byte{0xff}))
 			})
+		})
+
+		when("it is any other type", func(it It) {
 
-			it("should set lastSpec to zero value", func(assert Assert) {
-				assert.Empty(c.lastSpec)
+			it("should be rendered as Go syntax", func(assert Assert) {
+				assert.Equal("42", fuzzInput(42))
+				assert.Equal("true", fuzzInput(true))
 			})
 		})
 	})
@@ -237,3 +2406,38 @@ func BenchmarkComplexTest(b *testing.B) {
 		}
 	}
 }
+
+func Benchmark_Given_Benchmark(b *testing.B) {
+	SetSilent()
+	GivenBenchmark(b, "a slice of floats", func(when When) {
+		floats := make([]float64, 100)
+
+		when("summed", func(it It) {
+			summing := it.Measure(func() {
+				var sum float64
+				for _, f := range floats {
+					sum += f
+				}
+				_ = sum
+			})
+
+			it("should not allocate", summing.MaxAllocsPerOp(0))
+			it("should take under a millisecond", summing.MaxNsPerOp(1000000))
+		})
+	})
+}
+
+func Fuzz_Given_Fuzz(f *testing.F) {
+	GivenFuzz(f, "any input from this corpus", []string{"", "a", "a b c"}, func(when When, input string) {
+
+		when("split into fields and joined again", func(it It) {
+			joined := strings.Join(strings.Fields(input), " ")
+
+			it("should never panic", func(assert Assert) {})
+
+			it("should round-trip the joined input", func(assert Assert) {
+				assert.Equal(joined, strings.Join(strings.Fields(joined), " "))
+			})
+		})
+	})
+}
@@ -0,0 +1,92 @@
+package mspec
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/eduncan911/go-mspec/colors"
+)
+
+// Row is a row of the Examples of an Outline.  It maps the name of each
+// placeholder to its value.
+type Row map[string]interface{}
+
+// Examples are the rows an Outline runs its Given against.
+type Examples []Row
+
+// Outline is a Scenario Outline: a Given that runs once for each row of the
+// examples.  The <placeholders> in the text of the Given, and of its Whens
+// and Its, are replaced by the values of the row, and the closures get the
+// row to run with.
+//
+//	Outline(t, "a cart with <count> items", Examples{
+//		{"count": 1, "total": 10},
+//		{"count": 3, "total": 30},
+//	}, func(when When, row Row) {
+//		cart := NewCart(row["count"].(int))
+//
+//		when("totalled", func(it It) {
+//			it("should cost <total>", func(assert Assert) {
+//				assert.Equal(row["total"], cart.Total())
+//			})
+//		})
+//	})
+//
+// Each row renders, and is reported by go test, as its own Given.
+func Outline(t *testing.T, given string, examples Examples, when ...func(When, Row)) {
+	feature := featureDesc(2)
+	for _, row := range examples {
+		row := row
+
+		whens := make([]func(When), len(when))
+		for i, fn := range when {
+			fn := fn
+			whens[i] = func(w When) {
+				fn(w, row)
+			}
+		}
+		runGiven(t, feature, row.substitute(given), declaration{row: row}, whens)
+	}
+}
+
+// substitute replaces the placeholders of the row in text with their
+// values.  Placeholders that are not in the row are left as they are.
+func (row Row) substitute(text string) string {
+	if len(row) == 0 {
+		return text
+	}
+	pairs := make([]string, 0, len(row)*2)
+	for _, name := range row.names() {
+		pairs = append(pairs, "<"+name+">", fmt.Sprint(row[name]))
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// names returns the placeholders of the row in order.
+func (row Row) names() []string {
+	names := make([]string, 0, len(row))
+	for name := range row {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// String returns the row as it is rendered beneath its Given.
+func (row Row) String() string {
+	values := make([]string, len(row))
+	for i, name := range row.names() {
+		values[i] = fmt.Sprintf("<%s> %v", name, row[name])
+	}
+	return strings.Join(values, ", ")
+}
+
+// PrintExample prints the row of the Outline the Given runs.
+func (spec *Specification) PrintExample() {
+	if spec.row == nil {
+		return
+	}
+	spec.run.printf("%s    Example: %s%s\n", spec.run.config.AnsiOfGiven, spec.row, colors.Reset)
+}
@@ -0,0 +1,130 @@
+package mspec
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/eduncan911/go-mspec/colors"
+)
+
+// maxPanicFrames is how many frames of a panic's stack are printed.
+const maxPanicFrames = 10
+
+// mspecDir is the directory of the mspec sources, which are left out of the
+// stack of a panic along with the runtime and testing packages.
+var mspecDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// recoverPanic reports a panic of the spec as its failure.  It must be
+// deferred, and lets the remaining assertions and specs run.
+func (spec *Specification) recoverPanic() {
+	switch r := recover().(type) {
+	case nil:
+	case skipped:
+		spec.skipReason = r.reason
+	default:
+		spec.PrintPanic(r, panicStack())
+	}
+}
+
+// recoverPanic reports a panic in the closures of a Given or When as a
+// failure of it, and lets the remaining specs run.  It must be deferred.
+//
+// In a pass of an isolated run the panic fails the spec of the pass, unless
+// discovering the specs already reported it for the same Given or When.
+func (sc *scope) recoverPanic() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	panics := sc.spec.run.panics
+	switch {
+	case sc.plan != nil:
+		panics[sc.key()] = true
+	case sc.pass != nil:
+		if panics[sc.key()] {
+			return
+		}
+		sc.pass.done = true
+		sc.pass.spec.PrintPanic(r, panicStack())
+		return
+	}
+	spec := sc.spec
+	spec.T = sc.t
+	spec.PrintPanic(r, panicStack())
+}
+
+// PrintPanic fails the spec with the value it panicked with and the frames
+// of the panic's stack.
+func (spec *Specification) PrintPanic(value interface{}, stack []string) {
+	if spec.abandoned() {
+		return
+	}
+	if spec.T != nil {
+		spec.T.Fail()
+	}
+	message := fmt.Sprintf("panic: %v", value)
+	spec.AssertionFailed = true
+	spec.AssertionFailedMessages = append(spec.AssertionFailedMessages, message)
+
+	c := spec.run.config
+	switch {
+	case spec.Spec != "":
+		spec.PrintSpecWithError()
+	case spec.When != "":
+		spec.run.printf("%s    %s» %s %s «-- PANIC%s\n", c.AnsiOfThenWithError, spec.indent(), spec.whenKeyword(), spec.label(spec.When, c.AnsiOfThenWithError), colors.Reset)
+	default:
+		spec.run.printf("%s    » Given %s «-- PANIC%s\n", c.AnsiOfThenWithError, padLf(spec.label(spec.Given, c.AnsiOfThenWithError), 2), colors.Reset)
+	}
+
+	spec.run.printf("%s\t%s%s\n", c.AnsiOfExpectedError, message, colors.Reset)
+	if len(spec.assertFns) > 1 && spec.AssertFn != nil {
+		spec.run.printf("%s        in %s%s\n", c.AnsiOfCode, spec.assertionDesc(), colors.Reset)
+	}
+	spec.PrintBehavior()
+	spec.run.printf("%s        ---------\n", c.AnsiOfCode)
+	for _, frame := range stack {
+		spec.run.printf("%s        %s%s\n", c.AnsiOfCode, frame, colors.Reset)
+	}
+	spec.run.printf("\n\n")
+}
+
+// panicStack returns the frames of the panicking goroutine, leaving out the
+// runtime, the testing package and mspec itself.  It must be called from
+// the deferred func that recovered the panic.
+func panicStack() []string {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack []string
+	for len(stack) < maxPanicFrames {
+		frame, more := frames.Next()
+		if !internalFrame(frame) {
+			fn := frame.Function[strings.LastIndex(frame.Function, "/")+1:]
+			stack = append(stack, fmt.Sprintf("%s:%d %s", path.Base(frame.File), frame.Line, fn))
+		}
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+// internalFrame reports whether the frame is in the runtime, the testing
+// package, or the non-test sources of mspec and its assertions.
+func internalFrame(frame runtime.Frame) bool {
+	if strings.HasPrefix(frame.Function, "runtime.") || strings.HasPrefix(frame.Function, "testing.") {
+		return true
+	}
+	dir := filepath.Dir(frame.File)
+	if dir == filepath.Join(mspecDir, "assert") {
+		return true
+	}
+	return dir == mspecDir && !strings.HasSuffix(frame.File, "_test.go")
+}
@@ -0,0 +1,83 @@
+package mspec
+
+import (
+	"flag"
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var seedFlag = flag.Int64("mspec.seed", 0, "run the specs in a random order shuffled with `seed`, as printed in the Feature heading (or set MSPEC_SEED)")
+
+// SetRandomOrder runs the Whens of each Given, and the Its of each When, in
+// a random order to flush out specs that depend on the order they are
+// declared in.  The Given and When closures then run again for every spec,
+// as they do after SetIsolated().
+//
+// The seed of the order is printed in the Feature heading.  Pass it to the
+// -mspec.seed flag, or the MSPEC_SEED environment variable, to run the specs
+// in that same order again.
+func SetRandomOrder() {
+	configMu.Lock()
+	defer configMu.Unlock()
+	config.random = true
+}
+
+// SetDeclaredOrder runs the specs in the order they are declared (default).
+func SetDeclaredOrder() {
+	configMu.Lock()
+	defer configMu.Unlock()
+	config.random = false
+}
+
+var (
+	seedOnce sync.Once
+	seed     int64
+)
+
+// randomSeed returns the seed given by the -mspec.seed flag or the
+// MSPEC_SEED environment variable, either of which turns on the random
+// order.  Otherwise, when c runs the specs in a random order, the seed is
+// picked once for the whole test binary.
+func randomSeed(c MSpecConfig) (int64, bool) {
+	if *seedFlag != 0 {
+		return *seedFlag, true
+	}
+	if s, err := strconv.ParseInt(os.Getenv("MSPEC_SEED"), 10, 64); err == nil && s != 0 {
+		return s, true
+	}
+	if !c.random {
+		return 0, false
+	}
+	seedOnce.Do(func() {
+		seed = time.Now().UnixNano()
+	})
+	return seed, true
+}
+
+// shuffle seeds the order of the specs of a Given.  The order depends only
+// on the seed and on the Feature and Given, so a single seed replays every
+// Given no matter which of the tests run.
+func (r *runContext) shuffle(feature, given string) {
+	if !r.random {
+		return
+	}
+	h := fnv.New64a()
+	h.Write([]byte(feature + "\x00" + given))
+	r.rand = rand.New(rand.NewSource(r.seed ^ int64(h.Sum64())))
+}
+
+// order returns the order to run n Whens or Its in.
+func (r *runContext) order(n int) []int {
+	if r.rand != nil {
+		return r.rand.Perm(n)
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	return order
+}
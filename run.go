@@ -0,0 +1,156 @@
+package mspec
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/eduncan911/go-mspec/colors"
+)
+
+var (
+	// outputMu serializes the flushing of each Given's output so that
+	// concurrent Givens never interleave their lines.
+	outputMu sync.Mutex
+
+	// lastFeature is the Feature heading last written to the output.  It is
+	// the only rendering state shared across Givens and is guarded by outputMu.
+	lastFeature string
+)
+
+// runContext holds the state of a single call to Given.  Nothing in it is
+// shared with any other Given, which allows Givens to run concurrently from
+// tests that call t.Parallel().
+type runContext struct {
+	// config is a snapshot of the package configuration taken when the
+	// Given started, so the specs never read the global config.
+	config  MSpecConfig
+	feature string
+	focus   bool            // whether only the focused specs run
+	filter  *regexp.Regexp  // the specs to run, set by -mspec.focus
+	flagErr error           // why a flag could not be parsed, which fails the Given
+	labels  labelExpr       // the labels of the specs to run, set by -mspec.labels
+	ci      bool            // whether running on a CI server
+	strict  bool            // whether the specs NOT IMPLEMENTED fail
+	pending int             // how many specs are NOT IMPLEMENTED in strict mode
+	random  bool            // whether the specs run in a random order
+	seed    int64           // the seed of the random order
+	rand    *rand.Rand      // shuffles the specs of the Given, see shuffle
+	stopped map[string]bool // the Given and Whens stopped by a failure, see FailFast
+	ranAll  map[string]bool // the Given and Whens whose BeforeAll hooks ran
+	passes  map[string]int  // the isolated passes left through the Given and Whens
+	panics  map[string]bool // the Given and Whens that panicked while discovering their specs
+	fuzz    *testing.T      // the test of a fuzzed input, see GivenFuzz
+
+	// fixtures are the PerGiven fixtures, torn down by the cleanups once
+	// the Given completes.
+	fixtures fixtureSet
+	cleanups cleanups
+
+	mu       sync.Mutex
+	out      bytes.Buffer
+	lastWhen string
+	lastSpec string
+}
+
+func newRunContext() *runContext {
+	c := currentConfig()
+
+	// the order of the specs only matters when each spec gets a fresh
+	// context, so a random order implies the isolated mode.
+	seed, random := randomSeed(c)
+	if random {
+		c.isolated = true
+	}
+
+	filter, err := specFilter()
+	labels, labelsErr := labelFilter()
+	if err == nil {
+		err = labelsErr
+	}
+
+	return &runContext{
+		config:  c,
+		focus:   focusMode(),
+		filter:  filter,
+		flagErr: err,
+		labels:  labels,
+		ci:      inCI(),
+		strict:  strictMode(c),
+		random:  random,
+		seed:    seed,
+		stopped: map[string]bool{},
+		ranAll:  map[string]bool{},
+		passes:  map[string]int{},
+		panics:  map[string]bool{},
+	}
+}
+
+// printf renders to the Given's buffered output.  Nothing is written when the
+// output has been silenced.
+func (r *runContext) printf(format string, args ...interface{}) {
+	if r.config.output == outputNone {
+		return
+	}
+	r.mu.Lock()
+	fmt.Fprintf(&r.out, format, args...)
+	r.mu.Unlock()
+}
+
+// setLastSpec records the spec last printed.  It is guarded like the output,
+// as the assertions of a spec with a Timeout print from their own goroutine.
+func (r *runContext) setLastSpec(spec string) {
+	r.mu.Lock()
+	r.lastSpec = spec
+	r.mu.Unlock()
+}
+
+// isLastSpec reports whether spec is the one last printed.
+func (r *runContext) isLastSpec(spec string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastSpec == spec
+}
+
+// flush writes the Given's output in one piece, preceded by the Feature
+// heading if the previous output was for a different Feature.
+func (r *runContext) flush() {
+	if r.config.output == outputNone {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
+	if r.fuzz != nil {
+		r.logFailure()
+		return
+	}
+
+	w := r.config.writer()
+	if lastFeature != r.feature {
+		if r.random {
+			fmt.Fprintf(w, "%sFeature: %s (seed %d)%s\n", r.config.AnsiOfFeature, r.feature, r.seed, colors.Reset)
+		} else {
+			fmt.Fprintf(w, "%sFeature: %s%s\n", r.config.AnsiOfFeature, r.feature, colors.Reset)
+		}
+		lastFeature = r.feature
+	}
+	w.Write(r.out.Bytes())
+	r.out.Reset()
+}
+
+// writer returns where the configured output is written to.
+func (c *MSpecConfig) writer() io.Writer {
+	if c.output == outputStderr {
+		return os.Stderr
+	}
+	return os.Stdout
+}
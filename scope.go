@@ -0,0 +1,394 @@
+package mspec
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// scope is a Given or a When while it executes.  It is handed to the
+// Given and When closures as their when and it funcs.
+//
+// By default the closures run once and every spec shares the context they
+// set up.  In isolated mode the Given is first run to discover its specs
+// without executing any of them.  Then, for every spec, the Given is run
+// again from scratch with only that one spec and the Whens leading to it
+// executed.
+type scope struct {
+	t      *testing.T
+	spec   Specification // the Feature, Given and When of the specs declared here
+	parent *scope
+	mark   mark
+	hooks  hooks
+
+	// cleanups run once the scope completes, see It.Cleanup.
+	cleanups cleanups
+	fixtures fixtureSet // the PerSpec fixtures requested by its closures
+
+	// timeout is the deadline of each spec declared in the scope from
+	// then on.
+	timeout time.Duration
+
+	// failFast skips the remaining specs of the scope once one fails.
+	failFast bool
+
+	// index counts the Whens and Its declared in the scope so far, which is
+	// how a pass of an isolated run finds the spec it targets.
+	index  int
+	path   []int       // the indexes of the Whens leading to the scope, see key
+	target []int       // the path to the spec an isolated pass executes
+	pass   *passTarget // the spec an isolated pass executes
+	plan   *node       // records the specs while discovering an isolated run
+}
+
+// passTarget is the spec a pass of an isolated run executes, as discovered.
+// A pass whose closures no longer declare the spec fails it.
+type passTarget struct {
+	spec Specification
+	done bool // whether the spec ran, or the panic that kept it from running was reported
+}
+
+// mark is how a Given, When or It was declared: focused, skipped or neither.
+type mark struct {
+	focus  bool
+	skip   bool
+	reason string
+}
+
+// node is a When or It discovered in the first pass of an isolated run.
+type node struct {
+	keyword  string // the keyword of a When, And or But step
+	title    string
+	spec     bool
+	mark     mark
+	children []*node
+}
+
+func (n *node) add(keyword, title string, spec bool, m mark) *node {
+	c := &node{keyword: keyword, title: title, spec: spec, mark: m}
+	n.children = append(n.children, c)
+	return c
+}
+
+// given runs the Given closures in t, either once with a shared context or
+// once per spec when the run is isolated.
+func (sc *scope) given(t *testing.T, when []func(When)) {
+	sc.t = t
+	if !sc.spec.run.config.isolated {
+		defer sc.recoverPanic()
+		defer sc.finish()
+		sc.whens(when)
+		return
+	}
+
+	sc.replay(t, sc.spec, sc.discover(when), nil, when)
+}
+
+// discover runs the Given closures without executing any spec and returns
+// the specs they declare.
+func (sc *scope) discover(when []func(When)) *node {
+	discovery := sc.child()
+	discovery.plan = &node{}
+	defer discovery.recoverPanic()
+	defer discovery.finish()
+	discovery.whens(when)
+	return discovery.plan
+}
+
+// replay walks the discovered specs as subtests, in a random order if so
+// configured, and runs the Given again for every spec, targeting only that
+// spec.  spec holds the details of the
+// steps leading to n.
+func (sc *scope) replay(t *testing.T, spec Specification, n *node, path []int, when []func(When)) {
+	for _, i := range sc.spec.run.order(len(n.children)) {
+		c, path := n.children[i], append(path[:len(path):len(path)], i)
+
+		if c.spec {
+			subtest(t, c.title, func(t *testing.T) {
+				pass := sc.child()
+				pass.t = t
+				pass.target = path
+				pass.pass = &passTarget{spec: spec}
+				pass.pass.spec.T = t
+				pass.pass.spec.Spec = c.title
+				defer pass.missed()
+				defer pass.recoverPanic()
+				defer pass.finish()
+				pass.whens(when)
+			})
+			continue
+		}
+
+		subtest(t, c.title, func(t *testing.T) {
+			spec := spec.step(c.keyword, c.title)
+			if c.mark.skip {
+				spec.PrintWhenSkipped(c.mark.reason)
+				skip(t, c.mark.reason)
+				return
+			}
+			spec.PrintWhen()
+			sc.replay(t, spec, c, path, when)
+		})
+	}
+}
+
+// child returns a new scope nested in sc, inheriting its spec details.
+func (sc *scope) child() *scope {
+	return &scope{
+		t:        sc.t,
+		spec:     sc.spec,
+		parent:   sc,
+		pass:     sc.pass,
+		fixtures: fixtureSet{parent: &sc.fixtures},
+	}
+}
+
+// missed fails the spec of an isolated pass when the closures of the pass
+// returned without declaring it again.
+func (sc *scope) missed() {
+	if sc.pass.done {
+		return
+	}
+	sc.pass.spec.PrintSpecMissed()
+}
+
+// key identifies sc by where it was declared, which stays the same across
+// the passes of an isolated run that run every Given and When again in new
+// scopes.  Whens declared with the same text still get keys of their own.
+func (sc *scope) key() string {
+	return fmt.Sprint(sc.path)
+}
+
+// scopeProbe is the title the methods of When and It call them with to
+// reach the scope behind them.
+const scopeProbe = "\x00mspec.scope"
+
+// probe is handed to the closure passed along with scopeProbe.
+type probe struct {
+	Assert
+	scope *scope
+}
+
+// scope returns the Given scope that handed out the when func.
+func (w When) scope() *scope {
+	var sc *scope
+	w(scopeProbe, func(it It) {
+		sc = it.scope()
+	})
+	if sc == nil {
+		panic("mspec: When methods can only be called on the when func handed to a Given")
+	}
+	return sc
+}
+
+// scope returns the When scope that handed out the it func.
+func (it It) scope() *scope {
+	var sc *scope
+	it(scopeProbe, func(assert Assert) {
+		if p, ok := assert.(*probe); ok {
+			sc = p.scope
+		}
+	})
+	if sc == nil {
+		panic("mspec: It methods can only be called on the it func handed to a When")
+	}
+	return sc
+}
+
+// whens runs the closures of a Given.
+func (sc *scope) whens(when []func(When)) {
+	for _, whenFn := range when {
+		whenFn(sc.when)
+	}
+}
+
+// its runs the closures of a When.
+func (sc *scope) its(its []func(It)) {
+	for _, itFn := range its {
+		itFn(sc.it)
+	}
+}
+
+// when is the When func handed to the Given closures.
+func (sc *scope) when(when string, its ...func(It)) {
+	if when == scopeProbe {
+		sc.its(its)
+		return
+	}
+	sc.declareStep("When", when, mark{}, its)
+}
+
+// declareStep runs a When, or an And or But step, declared in sc.  The
+// closures of a skipped step are never run.
+func (sc *scope) declareStep(keyword, when string, m mark, its []func(It)) {
+	when = sc.spec.row.substitute(when)
+	i := sc.index
+	sc.index++
+
+	child := sc.child()
+	child.path = append(sc.path[:len(sc.path):len(sc.path)], i)
+	child.mark = m
+	child.spec = sc.spec.step(keyword, when)
+
+	switch {
+	case sc.plan != nil:
+		child.plan = sc.plan.add(keyword, when, false, m)
+		if !m.skip {
+			defer child.recoverPanic()
+			defer child.finish()
+			child.its(its)
+		}
+
+	case sc.target != nil:
+		if sc.target[0] != i || m.skip {
+			return
+		}
+		child.target = sc.target[1:]
+		defer child.recoverPanic()
+		defer child.finish()
+		child.its(its)
+
+	default:
+		subtest(sc.t, when, func(t *testing.T) {
+			child.t = t
+			if m.skip {
+				child.spec.PrintWhenSkipped(m.reason)
+				skip(t, m.reason)
+				return
+			}
+			child.spec.PrintWhen()
+			defer child.recoverPanic()
+			defer child.finish()
+			child.its(its)
+		})
+	}
+}
+
+// it is the It func handed to the When closures.
+func (sc *scope) it(it string, assertFns ...func(Assert)) {
+	if it == scopeProbe {
+		for _, fn := range assertFns {
+			fn(&probe{scope: sc})
+		}
+		return
+	}
+	sc.declareIt(it, mark{}, assertFns)
+}
+
+// declareIt runs an It declared in sc.
+func (sc *scope) declareIt(it string, m mark, assertFns []func(Assert)) {
+	it = sc.spec.row.substitute(it)
+	i := sc.index
+	sc.index++
+
+	switch {
+	case sc.plan != nil:
+		sc.plan.add("", it, true, m)
+		sc.countPass()
+
+	case sc.target != nil:
+		if len(sc.target) != 1 || sc.target[0] != i {
+			return
+		}
+		sc.pass.done = true
+		sc.runSpec(sc.t, it, m, assertFns)
+
+	default:
+		subtest(sc.t, it, func(t *testing.T) {
+			sc.runSpec(t, it, m, assertFns)
+		})
+	}
+}
+
+// runSpec executes a single It in t.
+func (sc *scope) runSpec(t *testing.T, it string, m mark, assertFns []func(Assert)) {
+
+	// each spec gets its own state so a failure is only ever
+	// reported against its own It.
+	spec := sc.spec
+	spec.T = t
+	spec.Spec = it
+	spec.focused = m.focus || sc.focused()
+
+	// a spec declared after a nested step is back in its own When
+	spec.PrintWhen()
+	// Spec output is handled in the spec.execute() below
+
+	if m.skip {
+		spec.PrintSpecSkipped(m.reason)
+		skip(t, m.reason)
+		return
+	}
+	if spec.run.focus && !spec.focused {
+		spec.PrintSpecSkipped(notFocused)
+		skip(t, notFocused)
+		return
+	}
+	if f := spec.run.filter; f != nil && !f.MatchString(spec.sentence()) {
+		spec.PrintSpecFiltered()
+		skip(t, "filtered by -mspec.focus")
+		return
+	}
+	if l := spec.run.labels; l != nil && !l.match(spec.labels()) {
+		spec.PrintSpecFiltered()
+		skip(t, "filtered by -mspec.labels")
+		return
+	}
+	if sc.stopped() {
+		spec.PrintSpecSkipped(failedFast)
+		skip(t, failedFast)
+		return
+	}
+
+	if len(assertFns) > 0 {
+		// having at least 1 assert means we are implemented
+		spec.assertFns = assertFns
+		spec.notImplemented = false
+	} else {
+		spec.assertFns = []func(Assert){notImplemented()}
+		spec.notImplemented = true
+	}
+
+	timeout := sc.specTimeout()
+	ctx, cancel := specContext(t, timeout)
+	defer cancel()
+	spec.ctx = ctx
+
+	defer sc.stopOnFailure(&spec)
+
+	// the cleanups of the spec run after its hooks, as those of its subtest
+	// when it has one of its own.
+	spec.cleanups = &cleanups{}
+	spec.fixtures = &fixtureSet{parent: &sc.fixtures}
+	if sc.target == nil && t != nil && t.Name() != "" {
+		t.Cleanup(spec.cleanUp)
+	} else {
+		defer spec.cleanUp()
+	}
+
+	// a panic, of a hook or an assertion, fails the spec and leaves the
+	// remaining specs to run.
+	defer spec.recoverPanic()
+	sc.beforeAll()
+	defer sc.afterEach()
+	sc.beforeEach()
+
+	// execute() handles contextual printing and some delegation
+	// to the Assert's implementation for error handling
+	spec.execute(timeout)
+
+	// t.Skip() must be called by the goroutine of the test, and ends it
+	if spec.skipReason != "" && !spec.AssertionFailed && !spec.abandoned() {
+		skip(t, spec.skipReason)
+	}
+}
+
+// focused reports whether sc or any scope it is nested in was focused.
+func (sc *scope) focused() bool {
+	for ; sc != nil; sc = sc.parent {
+		if sc.mark.focus {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,242 @@
+package mspec
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	asserts "github.com/eduncan911/go-mspec/assert"
+)
+
+var updateFlag = flag.Bool("mspec.update", false, "rewrite the snapshots of MatchSnapshot with the values they are given (or set MSPEC_UPDATE)")
+
+// snapshotDir is where the snapshots are kept, relative to the package
+// under test.
+const snapshotDir = "testdata"
+
+// updateSnapshots reports whether the snapshots are to be rewritten rather
+// than matched.
+func updateSnapshots() bool {
+	if *updateFlag {
+		return true
+	}
+	update, _ := strconv.ParseBool(os.Getenv("MSPEC_UPDATE"))
+	return update
+}
+
+// MatchSnapshot asserts that value matches the snapshot of the spec, a
+// golden file under testdata/ named after its Feature, Given, Whens and It.
+// Strings and bytes are matched as they are, anything else as indented
+// JSON.  A mismatch fails with a diff of the lines.
+//
+//	SpecOf(assert).MatchSnapshot(page.Render())
+//
+// Run go test -mspec.update to record, or rewrite, the snapshots.
+//
+// Returns whether the assertion was successful (true) or not (false).
+func (spec *Specification) MatchSnapshot(value interface{}, msgAndArgs ...interface{}) bool {
+	spec.snapshots++
+	path := spec.snapshotPath(spec.snapshots)
+	actual := snapshotOf(value)
+
+	if updateSnapshots() {
+		if err := writeSnapshot(path, actual); err != nil {
+			return asserts.Fail(spec.testingT(), fmt.Sprintf("Could not update the snapshot: %v", err), msgAndArgs...)
+		}
+		return true
+	}
+
+	expected, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return asserts.Fail(spec.testingT(), fmt.Sprintf("There is no snapshot %s\nrun go test -mspec.update to record it", path), msgAndArgs...)
+	}
+	if err != nil {
+		return asserts.Fail(spec.testingT(), fmt.Sprintf("Could not read the snapshot: %v", err), msgAndArgs...)
+	}
+	if bytes.Equal(expected, actual) {
+		return true
+	}
+	return asserts.Fail(spec.testingT(), fmt.Sprintf("Does not match the snapshot %s\n(- snapshot, + actual, run go test -mspec.update to accept)\n%s",
+		path, diffLines(string(expected), string(actual))), msgAndArgs...)
+}
+
+// snapshotPath returns the path of the n-th snapshot of the spec:
+//
+//	testdata/Feature/Given/When/It.golden
+//	testdata/Feature/Given/When/It_2.golden
+func (spec *Specification) snapshotPath(n int) string {
+	// named as the subtests are, the steps without their keywords
+	names := []string{spec.Feature, spec.Given}
+	for _, step := range spec.steps {
+		names = append(names, step[strings.Index(step, " ")+1:])
+	}
+	if len(spec.steps) == 0 {
+		names = append(names, spec.When)
+	}
+	names = append(names, spec.Spec)
+
+	parts := make([]string, 0, len(names)+1)
+	parts = append(parts, snapshotDir)
+	for _, name := range names {
+		parts = append(parts, fileName(name))
+	}
+	if n > 1 {
+		parts[len(parts)-1] += fmt.Sprintf("_%d", n)
+	}
+	return filepath.Join(parts...) + ".golden"
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// fileName makes text safe to name a file or directory with, the way go
+// test names subtests.
+func fileName(text string) string {
+	name := strings.Replace(subtestName(text), " ", "_", -1)
+	name = unsafeFileChars.ReplaceAllString(name, "_")
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+// snapshotOf renders value as its snapshot is written.  Strings and bytes
+// are kept as they are, and anything else is rendered as indented JSON.
+func snapshotOf(value interface{}) []byte {
+	switch v := value.(type) {
+	case string:
+		return []byte(v)
+	case []byte:
+		return v
+	}
+	b, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return []byte(fmt.Sprintf("%#v\n", value))
+	}
+	return append(b, '\n')
+}
+
+func writeSnapshot(path string, snapshot []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, snapshot, 0644)
+}
+
+// diffContext is how many unchanged lines are shown around each change.
+const diffContext = 2
+
+// diffCells caps the size of the table diffChanges compares the lines
+// with, beyond which only the first of the lines that differ are shown.
+const diffCells = 1 << 22
+
+// diffLine is a line of a diff, with the op it is shown with: ' ' when it
+// is unchanged, '-' when it is only expected and '+' when it is only
+// actual.
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// diffLines renders the lines that differ between expected and actual,
+// along with the unchanged lines around them.
+func diffLines(expected, actual string) string {
+	a, b := strings.Split(expected, "\n"), strings.Split(actual, "\n")
+
+	// only the lines between those both start and end with are compared
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	var lines []diffLine
+	for _, text := range a[:pre] {
+		lines = append(lines, diffLine{' ', text})
+	}
+	lines = append(lines, diffChanges(a[pre:len(a)-suf], b[pre:len(b)-suf])...)
+	for _, text := range a[len(a)-suf:] {
+		lines = append(lines, diffLine{' ', text})
+	}
+
+	// only the changes, and the context around them, are shown
+	show := make([]bool, len(lines))
+	for k, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		for c := k - diffContext; c <= k+diffContext; c++ {
+			if c >= 0 && c < len(lines) {
+				show[c] = true
+			}
+		}
+	}
+
+	var out []string
+	for k, l := range lines {
+		if !show[k] {
+			if k == 0 || show[k-1] {
+				out = append(out, "  ...")
+			}
+			continue
+		}
+		out = append(out, fmt.Sprintf("%c %s", l.op, l.text))
+	}
+	return strings.Join(out, "\n")
+}
+
+// diffChanges returns the lines of a and b as the changes from a to b, along
+// their longest common subsequence.  When there are too many lines to
+// compare, only the first line of each is shown.
+func diffChanges(a, b []string) []diffLine {
+	if len(a)*len(b) > diffCells {
+		return []diffLine{
+			{'-', a[0]},
+			{'+', b[0]},
+			{' ', fmt.Sprintf("... and %d more lines of the snapshot, %d of the actual value, too many to compare", len(a)-1, len(b)-1)},
+		}
+	}
+
+	// the lengths of the longest common subsequences of the suffixes
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	return lines
+}
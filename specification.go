@@ -1,10 +1,12 @@
 package mspec
 
 import (
+	"context"
 	"fmt"
 	"github.com/eduncan911/go-mspec/colors"
 	"io/ioutil"
 	"path"
+	"reflect"
 	"runtime"
 	"strings"
 	"testing"
@@ -41,79 +43,170 @@ type Specification struct {
 	AssertionFailedMessages []string
 
 	notImplemented bool
+	focused        bool
+	skipReason     string      // why the spec skipped itself, see Skip
+	cleanups       *cleanups   // registered while the spec runs, see Cleanup
+	fixtures       *fixtureSet // the PerSpec fixtures built for the spec, see Let
+	behavior       string      // the Behavior the spec was declared in, see For
+	snapshots      int         // how many snapshots the spec matched, see MatchSnapshot
+	assertFns      []func(Assert)
+	assertion      int        // the index of AssertFn in assertFns
+	keyword        string     // the keyword of the When: When, And or But
+	steps          []string   // the When, And and But steps leading to the spec
+	depth          int        // how deeply the When is nested in other Whens
+	row            Row        // the row of the Examples of an Outline
+	b              *testing.B // the benchmark of a GivenBenchmark
+	ctx            context.Context
+	timedOut       *int32 // set once the spec is abandoned, see runWithin
+	run            *runContext
 }
 
+// PrintFeature queues the Feature heading.  It is written when the Given's
+// output is flushed, and only if the Feature differs from the one before it.
 func (spec *Specification) PrintFeature() {
-	if config.lastFeature == spec.Feature {
-		return
-	}
-	if config.output != outputNone {
-		fmt.Printf("%sFeature: %s%s\n", config.AnsiOfFeature, spec.Feature, colors.Reset)
-	}
-	config.lastFeature = spec.Feature
+	spec.run.feature = spec.Feature
 }
 
 func (spec *Specification) PrintContext() {
-	if config.lastGiven == spec.Given {
-		return
-	}
-	if config.output != outputNone {
-		fmt.Printf("%s  Given %s%s\n", config.AnsiOfGiven, padLf(spec.Given, 2), colors.Reset)
-	}
-	config.lastGiven = spec.Given
+	spec.run.printf("%s  Given %s%s\n", spec.run.config.AnsiOfGiven, padLf(spec.label(spec.Given, spec.run.config.AnsiOfGiven), 2), colors.Reset)
+}
+
+func (spec *Specification) PrintContextSkipped(reason string) {
+	spec.run.printf("%s  Given %s «-- SKIPPED: %s%s\n", spec.run.config.AnsiOfThenSkipped, padLf(spec.label(spec.Given, spec.run.config.AnsiOfThenSkipped), 2), reason, colors.Reset)
 }
 
 func (spec *Specification) PrintWhen() {
-	if config.lastWhen == spec.When {
+	if spec.run.lastWhen == spec.whens() {
 		return
 	}
-	if config.output != outputNone {
-		fmt.Printf("%s    When %s%s\n", config.AnsiOfWhen, spec.When, colors.Reset)
-	}
-	config.lastWhen = spec.When
+	spec.run.printf("%s    %s%s %s%s\n", spec.run.config.AnsiOfWhen, spec.indent(), spec.whenKeyword(), spec.label(spec.When, spec.run.config.AnsiOfWhen), colors.Reset)
+	spec.run.lastWhen = spec.whens()
+}
+
+func (spec *Specification) PrintWhenSkipped(reason string) {
+	spec.run.printf("%s    %s%s %s «-- SKIPPED: %s%s\n", spec.run.config.AnsiOfThenSkipped, spec.indent(), spec.whenKeyword(), spec.label(spec.When, spec.run.config.AnsiOfThenSkipped), reason, colors.Reset)
+	spec.run.lastWhen = spec.whens()
 }
 
 func (spec *Specification) PrintSpec() {
-	if config.output != outputNone {
-		fmt.Printf("%s    » It %s %s\n", config.AnsiOfThen, spec.Spec, colors.Reset)
-	}
-	config.lastSpec = spec.Spec
+	spec.run.printf("%s    %s» It %s %s\n", spec.run.config.AnsiOfThen, spec.indent(), spec.label(spec.Spec, spec.run.config.AnsiOfThen), colors.Reset)
+	spec.run.setLastSpec(spec.Spec)
 }
 
 func (spec *Specification) PrintSpecWithError() {
-	if config.lastSpec == spec.Spec {
+	if spec.run.isLastSpec(spec.Spec) {
 		return
 	}
-	if config.output != outputNone {
-		fmt.Printf("%s    » It %s %s\n", config.AnsiOfThenWithError, spec.Spec, colors.Reset)
-	}
-	config.lastSpec = spec.Spec
+	spec.run.printf("%s    %s» It %s %s\n", spec.run.config.AnsiOfThenWithError, spec.indent(), spec.label(spec.Spec, spec.run.config.AnsiOfThenWithError), colors.Reset)
+	spec.run.setLastSpec(spec.Spec)
 }
 
 func (spec *Specification) PrintSpecNotImplemented() {
-	if config.output != outputNone {
-		fmt.Printf("%s    » It %s «-- NOT IMPLEMENTED%s\n", config.AnsiOfThenNotImplemented, spec.Spec, colors.Reset)
+	spec.run.printf("%s    %s» It %s «-- NOT IMPLEMENTED%s\n", spec.run.config.AnsiOfThenNotImplemented, spec.indent(), spec.label(spec.Spec, spec.run.config.AnsiOfThenNotImplemented), colors.Reset)
+	spec.run.setLastSpec(spec.Spec)
+}
+
+func (spec *Specification) PrintSpecFocused() {
+	spec.run.printf("%s    %s» It %s «-- FOCUSED%s\n", spec.run.config.AnsiOfThenFocused, spec.indent(), spec.label(spec.Spec, spec.run.config.AnsiOfThenFocused), colors.Reset)
+	spec.run.setLastSpec(spec.Spec)
+}
+
+func (spec *Specification) PrintSpecSkipped(reason string) {
+	spec.run.printf("%s    %s» It %s «-- SKIPPED: %s%s\n", spec.run.config.AnsiOfThenSkipped, spec.indent(), spec.label(spec.Spec, spec.run.config.AnsiOfThenSkipped), reason, colors.Reset)
+	spec.run.setLastSpec(spec.Spec)
+}
+
+func (spec *Specification) PrintSpecFiltered() {
+	spec.run.printf("%s    %s» It %s «-- FILTERED%s\n", spec.run.config.AnsiOfThenSkipped, spec.indent(), spec.label(spec.Spec, spec.run.config.AnsiOfThenSkipped), colors.Reset)
+	spec.run.setLastSpec(spec.Spec)
+}
+
+// PrintSpecMissed fails a spec of an isolated run that its Given and When
+// closures no longer declare when they run again for it.
+func (spec *Specification) PrintSpecMissed() {
+	if spec.T != nil {
+		spec.T.Fail()
 	}
-	config.lastSpec = spec.Spec
+	message := "the Given and When closures did not declare the spec again when they ran for it"
+	spec.AssertionFailed = true
+	spec.AssertionFailedMessages = append(spec.AssertionFailedMessages, message)
+
+	c := spec.run.config
+	spec.run.printf("%s    %s» It %s «-- NOT DECLARED%s\n", c.AnsiOfThenWithError, spec.indent(), spec.label(spec.Spec, c.AnsiOfThenWithError), colors.Reset)
+	spec.run.printf("%s\t%s%s\n", c.AnsiOfExpectedError, message, colors.Reset)
+	spec.PrintBehavior()
+	spec.run.printf("\n\n")
 }
 
 func (spec *Specification) PrintError(message string) {
+	if spec.abandoned() {
+		return
+	}
+	if spec.T != nil {
+		spec.T.Fail()
+	}
+
 	failingLine, err := getFailingLine()
 
 	if err != nil {
 		return
 	}
-	if config.output != outputNone {
-		fmt.Printf("%s%s%s\n", config.AnsiOfExpectedError, message, colors.Reset)
-		fmt.Printf("%s        in %s:%d%s\n", config.AnsiOfCode, path.Base(failingLine.filename), failingLine.number, colors.Reset)
-		fmt.Printf("%s        ---------\n", config.AnsiOfCode)
-		fmt.Printf("%s        %d. %s%s\n", config.AnsiOfCode, failingLine.number-1, softTabs(failingLine.prev), colors.Reset)
-		fmt.Printf("%s        %d. %s %s\n", config.AnsiOfCodeError, failingLine.number, failingLine.content, colors.Reset)
-		fmt.Printf("%s        %d. %s%s\n", config.AnsiOfCode, failingLine.number+1, softTabs(failingLine.next), colors.Reset)
-		fmt.Println()
+	spec.printFailingLine(message, failingLine)
+}
+
+// failAt fails the spec with message as an assertion failing at line of
+// file would, for the asserts that mspec runs on behalf of a spec.
+func (spec *Specification) failAt(file string, line int, message string) {
+	if spec.abandoned() {
+		return
+	}
+	if spec.T != nil {
 		spec.T.Fail()
-		fmt.Println()
 	}
+	spec.AssertionFailed = true
+	spec.PrintSpecWithError()
+
+	failingLine, err := readFailingLine(file, line)
+	if err != nil {
+		return
+	}
+	spec.printFailingLine(message, failingLine)
+}
+
+// printFailingLine prints message and the lines around where the spec failed.
+func (spec *Specification) printFailingLine(message string, failingLine failingLine) {
+	c := spec.run.config
+	spec.run.printf("%s%s%s\n", c.AnsiOfExpectedError, message, colors.Reset)
+	if len(spec.assertFns) > 1 {
+		spec.run.printf("%s        in %s%s\n", c.AnsiOfCode, spec.assertionDesc(), colors.Reset)
+	}
+	spec.run.printf("%s        in %s:%d%s\n", c.AnsiOfCode, path.Base(failingLine.filename), failingLine.number, colors.Reset)
+	spec.PrintBehavior()
+	spec.run.printf("%s        ---------\n", c.AnsiOfCode)
+	spec.run.printf("%s        %d. %s%s\n", c.AnsiOfCode, failingLine.number-1, softTabs(failingLine.prev), colors.Reset)
+	spec.run.printf("%s        %d. %s %s\n", c.AnsiOfCodeError, failingLine.number, failingLine.content, colors.Reset)
+	spec.run.printf("%s        %d. %s%s\n", c.AnsiOfCode, failingLine.number+1, softTabs(failingLine.next), colors.Reset)
+	spec.run.printf("\n\n")
+}
+
+// assertionDesc describes which of the assertion funcs of the spec is running,
+// by its name unless it is a func literal:
+//
+//	assertion 2 of 3, hasTotal
+func (spec *Specification) assertionDesc() string {
+	desc := fmt.Sprintf("assertion %d of %d", spec.assertion+1, len(spec.assertFns))
+
+	fn := runtime.FuncForPC(reflect.ValueOf(spec.AssertFn).Pointer())
+	if fn == nil {
+		return desc
+	}
+	name := fn.Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+	name = name[strings.Index(name, ".")+1:]
+	if strings.Contains(name, ".func") {
+		return desc
+	}
+	return desc + ", " + name
 }
 
 func getFailingLine() (failingLine, error) {
@@ -133,6 +226,11 @@ func getFailingLine() (failingLine, error) {
 		_, filename, ln, _ = runtime.Caller(6)
 	}
 
+	return readFailingLine(filename, ln)
+}
+
+// readFailingLine reads the line ln of filename along with the lines around it.
+func readFailingLine(filename string, ln int) (failingLine, error) {
 	bf, err := ioutil.ReadFile(filename)
 
 	if err != nil {
@@ -0,0 +1,72 @@
+package mspec
+
+import "strings"
+
+// When declares a When nested in the When of it.  Its closures run after
+// those of the parent When, building on the state it set up, and it renders
+// indented beneath the parent.
+//
+//	when("the dog is washed", func(it It) {
+//		d.Wash()
+//		it("should be clean", ...)
+//
+//		it.When("it rolls in mud", func(it It) {
+//			d.Roll()
+//			it("should be dirty", ...)
+//		})
+//	})
+func (it It) When(when string, its ...func(It)) {
+	it.scope().declareStep("When", when, mark{}, its)
+}
+
+// And declares a step that continues the When of it, like a nested When,
+// and renders at the same indentation as the parent.
+//
+//	When the dog is washed
+//	» It should be clean
+//	And it rolls in mud
+//	» It should be dirty
+func (it It) And(step string, its ...func(It)) {
+	it.scope().declareStep("And", step, mark{}, its)
+}
+
+// But declares a step that continues the When of it, as And does.
+func (it It) But(step string, its ...func(It)) {
+	it.scope().declareStep("But", step, mark{}, its)
+}
+
+// step returns the spec details of the step declared as keyword, one of
+// When, And or But, nested in the When of spec.  Only a nested When is
+// indented.
+func (spec Specification) step(keyword, text string) Specification {
+	if keyword == "When" && spec.When != "" {
+		spec.depth++
+	}
+	spec.When = text
+	spec.keyword = keyword
+	spec.steps = append(spec.steps[:len(spec.steps):len(spec.steps)], keyword+" "+text)
+	return spec
+}
+
+// whenKeyword is the keyword the When of the spec was declared with.
+func (spec *Specification) whenKeyword() string {
+	if spec.keyword == "" {
+		return "When"
+	}
+	return spec.keyword
+}
+
+// whens returns the steps leading to the spec as a single line, such as
+// "When the dog is washed And it rolls in mud".
+func (spec *Specification) whens() string {
+	if len(spec.steps) == 0 {
+		return "When " + spec.When
+	}
+	return strings.Join(spec.steps, " ")
+}
+
+// indent is the indentation of the spec's When and Its, beyond that of a
+// When declared in a Given.
+func (spec *Specification) indent() string {
+	return strings.Repeat("  ", spec.depth)
+}
@@ -0,0 +1,104 @@
+package mspec
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/eduncan911/go-mspec/colors"
+)
+
+var strictFlag = flag.Bool("mspec.strict", false, "fail every spec that is NOT IMPLEMENTED (or set MSPEC_STRICT)")
+
+// SetStrict fails every spec that is NOT IMPLEMENTED, and prints how many of
+// them each Feature has.  Use it on release branches so that they never ship
+// with stubbed specs.  It can also be turned on with the -mspec.strict flag
+// or the MSPEC_STRICT environment variable.
+func SetStrict() {
+	configMu.Lock()
+	defer configMu.Unlock()
+	config.strict = true
+}
+
+// SetLenient lets the specs that are NOT IMPLEMENTED pass (default).
+func SetLenient() {
+	configMu.Lock()
+	defer configMu.Unlock()
+	config.strict = false
+}
+
+// strictMode reports whether c, the -mspec.strict flag or the MSPEC_STRICT
+// environment variable turned on the strict mode.
+func strictMode(c MSpecConfig) bool {
+	if c.strict || *strictFlag {
+		return true
+	}
+	strict, _ := strconv.ParseBool(os.Getenv("MSPEC_STRICT"))
+	return strict
+}
+
+var (
+	// pendingMu guards the counts of the NOT IMPLEMENTED specs of the
+	// Features of the tests running.
+	pendingMu sync.Mutex
+	pending   = map[*testing.T]map[string]int{}
+)
+
+// countPending adds the NOT IMPLEMENTED specs of the Given that ran in t to
+// the summary of its Feature, which is printed once the test completes.
+// Without a test to hook on to, the summary of the Given is printed at once.
+func (r *runContext) countPending(t *testing.T) {
+	if !r.strict || r.pending == 0 {
+		return
+	}
+	if t == nil || t.Name() == "" {
+		r.printPending(r.feature, r.pending)
+		return
+	}
+
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+
+	counts, ok := pending[t]
+	if !ok {
+		counts = map[string]int{}
+		pending[t] = counts
+		t.Cleanup(func() {
+			pendingMu.Lock()
+			defer pendingMu.Unlock()
+			for feature, count := range pending[t] {
+				r.printPending(feature, count)
+			}
+			delete(pending, t)
+		})
+	}
+	counts[r.feature] += r.pending
+}
+
+// printPending prints the summary of the NOT IMPLEMENTED specs of a Feature.
+func (r *runContext) printPending(feature string, count int) {
+	if r.config.output == outputNone {
+		return
+	}
+	specs := "specs"
+	if count == 1 {
+		specs = "spec"
+	}
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	fmt.Fprintf(r.config.writer(), "%sFeature: %s has %d %s NOT IMPLEMENTED%s\n\n",
+		r.config.AnsiOfThenWithError, feature, count, specs, colors.Reset)
+}
+
+// failNotImplemented fails a spec that is NOT IMPLEMENTED in strict mode.
+func (spec *Specification) failNotImplemented() {
+	if spec.T != nil {
+		spec.T.Fail()
+	}
+	spec.run.printf("%s    %s» It %s «-- NOT IMPLEMENTED%s\n", spec.run.config.AnsiOfThenWithError, spec.indent(), spec.label(spec.Spec, spec.run.config.AnsiOfThenWithError), colors.Reset)
+	spec.run.setLastSpec(spec.Spec)
+}
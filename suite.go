@@ -0,0 +1,63 @@
+package mspec
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// Suite is a Given written once against the interface I, that runs for
+// every implementation of I it is handed.
+type Suite[I any] struct {
+	given string
+	when  []func(When, func() I)
+}
+
+// DefineSuite defines the specs of a Suite for the interface I.  The
+// closures get the constructor of the implementation under test:
+//
+//	var aStore = DefineSuite[Store]("a Store", func(when When, newStore func() Store) {
+//
+//		when("a key is put", func(it It) {
+//			store := newStore()
+//			store.Put("key", "value")
+//
+//			it("should get the value of the key", func(assert Assert) {
+//				value, _ := store.Get("key")
+//				assert.Equal("value", value)
+//			})
+//		})
+//	})
+//
+// DefineSuite panics if I is not an interface.
+func DefineSuite[I any](given string, when ...func(When, func() I)) Suite[I] {
+	if t := reflect.TypeOf((*I)(nil)).Elem(); t.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("mspec: a Suite is defined for an interface, not %v", t))
+	}
+	return Suite[I]{given: given, when: when}
+}
+
+// Run runs the suite against the implementation that constructor makes,
+// as a subtest named after the implementation:
+//
+//	func Test_Stores(t *testing.T) {
+//		aStore.Run(t, "in-memory", func() Store { return NewMemoryStore() })
+//		aStore.Run(t, "file-backed", func() Store { return NewFileStore(t.TempDir()) })
+//	}
+//
+// Each implementation is reported under a Feature heading of its own.
+func (s Suite[I]) Run(t *testing.T, implementation string, constructor func() I) {
+	feature := fmt.Sprintf("%s (%s)", featureDesc(2), implementation)
+
+	whens := make([]func(When), 0, len(s.when))
+	for _, fn := range s.when {
+		fn := fn
+		whens = append(whens, func(w When) {
+			fn(w, constructor)
+		})
+	}
+
+	subtest(t, implementation, func(t *testing.T) {
+		runGiven(t, feature, s.given, declaration{}, whens)
+	})
+}
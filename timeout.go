@@ -0,0 +1,149 @@
+package mspec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eduncan911/go-mspec/colors"
+)
+
+// Timeout gives each spec of the Given declared after it a deadline of d.
+// A spec that has not completed by then is reported as timed out along
+// with the stacks of its goroutines, and the remaining specs run.
+//
+// The Context() of the spec is cancelled at the deadline, so code under
+// test that takes a context can give up in time.
+func (w When) Timeout(d time.Duration) {
+	w.scope().timeout = d
+}
+
+// Timeout gives each spec of the When declared after it a deadline of d,
+// overriding the Timeout of the Given.  See When.Timeout.
+func (it It) Timeout(d time.Duration) {
+	it.scope().timeout = d
+}
+
+// specTimeout returns the Timeout of sc or of the nearest scope it is
+// nested in.  Zero means the spec has no deadline of its own.
+func (sc *scope) specTimeout() time.Duration {
+	for ; sc != nil; sc = sc.parent {
+		if sc.timeout > 0 {
+			return sc.timeout
+		}
+	}
+	return 0
+}
+
+// Context returns the context of the spec.  It is cancelled at the spec's
+// Timeout, at the deadline of go test's -timeout flag, or once the spec
+// completes, whichever comes first.
+//
+//	status, err := client.Status(SpecOf(assert).Context())
+func (spec *Specification) Context() context.Context {
+	if spec.ctx == nil {
+		return context.Background()
+	}
+	return spec.ctx
+}
+
+// specContext returns the context of a spec run in t with a timeout of d.
+func specContext(t *testing.T, d time.Duration) (context.Context, context.CancelFunc) {
+	ctx := context.Background()
+	cancel := func() {}
+
+	// a hand-built testing.T has no deadline to read
+	if t != nil && t.Name() != "" {
+		if deadline, ok := t.Deadline(); ok {
+			ctx, cancel = context.WithDeadline(ctx, deadline)
+		}
+	}
+	if d <= 0 {
+		return ctx, cancel
+	}
+	ctx, cancelTimeout := context.WithTimeout(ctx, d)
+	return ctx, func() {
+		cancelTimeout()
+		cancel()
+	}
+}
+
+// runWithin runs the assertions of the spec on their own goroutine and
+// waits up to d for them to complete.  They run on a copy of the spec that
+// is only handed back once they complete.  A spec that times out is
+// abandoned: it is left running on its copy, and anything it reports
+// afterwards is dropped.
+func (spec *Specification) runWithin(d time.Duration) {
+	spec.timedOut = new(int32)
+	done := make(chan Specification, 1)
+	id := make(chan int, 1)
+	own := *spec
+	go func() {
+		defer func() { done <- own }()
+		id <- goroutineID()
+		own.assertAll()
+	}()
+	g := <-id
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case *spec = <-done:
+	case <-timer.C:
+		atomic.StoreInt32(spec.timedOut, 1)
+		spec.PrintSpecTimedOut(d, goroutines(g))
+	}
+}
+
+// abandoned reports whether the spec timed out and is no longer reported.
+func (spec *Specification) abandoned() bool {
+	return spec.timedOut != nil && atomic.LoadInt32(spec.timedOut) == 1
+}
+
+// PrintSpecTimedOut fails the spec that did not complete within d, and
+// prints the stacks of its goroutines.
+func (spec *Specification) PrintSpecTimedOut(d time.Duration, stacks string) {
+	if spec.T != nil {
+		spec.T.Fail()
+	}
+	c := spec.run.config
+	spec.run.printf("%s    %s» It %s «-- TIMED OUT after %v%s\n", c.AnsiOfThenWithError, spec.indent(), spec.label(spec.Spec, c.AnsiOfThenWithError), d, colors.Reset)
+	spec.run.printf("%s        ---------\n", c.AnsiOfCode)
+	for _, line := range strings.Split(stacks, "\n") {
+		spec.run.printf("%s        %s%s\n", c.AnsiOfCode, softTabs(line), colors.Reset)
+	}
+	spec.run.printf("\n")
+}
+
+// goroutineID returns the id of the calling goroutine, as printed in the
+// header of its stack.
+func goroutineID() int {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	var id int
+	fmt.Sscanf(string(bytes.TrimPrefix(buf, []byte("goroutine "))), "%d", &id)
+	return id
+}
+
+// goroutines returns the stacks of the goroutine id and of the goroutines
+// it started.
+func goroutines(id int) string {
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+
+	header := fmt.Sprintf("goroutine %d [", id)
+	child := fmt.Sprintf(" in goroutine %d\n", id)
+
+	var stacks []string
+	for _, g := range strings.Split(strings.TrimSpace(string(buf)), "\n\n") {
+		if strings.HasPrefix(g, header) || strings.Contains(g, child) {
+			stacks = append(stacks, g)
+		}
+	}
+	return strings.Join(stacks, "\n\n")
+}